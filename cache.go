@@ -0,0 +1,166 @@
+package defaults
+
+import (
+	"reflect"
+	"regexp"
+	"sync"
+)
+
+var (
+	sliceLiteral = regexp.MustCompile(`^\[.*]$`)
+	mapLiteral   = regexp.MustCompile(`^{.*}$`)
+)
+
+// structPlan is the reflection result of a struct type which is reused across every filling of the type
+type structPlan struct {
+	fields    []fieldPlan
+	defaulter bool
+	generated bool
+}
+
+type fieldPlan struct {
+	index int
+	field reflect.StructField
+	tag   string // value of the default tag
+}
+
+type literalPlan struct {
+	tokens []string
+	err    error
+}
+
+// literalKey identifies a value parsed from the literal, strict parsing may fail where lenient one does not
+type literalKey struct {
+	typ     reflect.Type
+	literal string
+	strict  bool
+}
+
+// cache holds the plans of a filler, it is safe for concurrent use
+type cache struct {
+	structs  sync.Map // reflect.Type -> *structPlan
+	literals sync.Map // string -> *literalPlan
+}
+
+// structPlan returns the exported fields of the struct type together with their tags
+func (f *filler) structPlan(typ reflect.Type) *structPlan {
+	if plan, ok := f.cache.structs.Load(typ); ok {
+		return plan.(*structPlan)
+	}
+
+	plan := &structPlan{
+		defaulter: reflect.PtrTo(typ).Implements(defaulterType),
+		generated: reflect.PtrTo(typ).Implements(generatedType),
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		fieldType := typ.Field(i)
+		// Only fill the filed if the field can be set, i.e. exported
+		if fieldType.PkgPath != "" {
+			continue
+		}
+		plan.fields = append(plan.fields, fieldPlan{
+			index: i,
+			field: fieldType,
+			tag:   fieldType.Tag.Get(f.DefaultTag),
+		})
+	}
+
+	actual, _ := f.cache.structs.LoadOrStore(typ, plan)
+	return actual.(*structPlan)
+}
+
+// tokenizeLiteral works like tokenizeValues but only tokenizes the same literal once, as long as it comes from the default tag
+// of the struct plan. Literals of environment variables, values or interpolation are not cached, they could grow it without bound.
+func (f *filler) tokenizeLiteral(field *Field, expr string) ([]string, error) {
+	if !f.isPlanned(field) {
+		return tokenizeValues(expr)
+	}
+	if plan, ok := f.cache.literals.Load(expr); ok {
+		return plan.(*literalPlan).tokens, plan.(*literalPlan).err
+	}
+
+	tokens, err := tokenizeValues(expr)
+	f.cache.literals.Store(expr, &literalPlan{tokens: tokens, err: err})
+	return tokens, err
+}
+
+// isPlanned tells whether the tag of the field is, or is part of, the default tag of the struct field it belongs to
+func (f *filler) isPlanned(field *Field) bool {
+	for p := field; p != nil; p = p.Parent {
+		if p.StructField.Name != "" {
+			return p.Tag == p.StructField.Tag.Get(f.DefaultTag)
+		}
+	}
+	return false
+}
+
+// loadLiteral fills the field with a copy of the value parsed from the same default tag before, if any
+func (f *filler) loadLiteral(field *Field) bool {
+	if !f.isParsedOnce(field) {
+		return false
+	}
+
+	value, ok := f.parsed.Load(literalKey{field.Value.Type(), field.Tag, f.isStrict(field)})
+	if ok {
+		field.Value.Set(copyValue(value.(reflect.Value)))
+	}
+	return ok
+}
+
+// storeLiteral keeps a copy of the value just parsed from the default tag, unless parsing reported any error
+// which would be lost by filling from the copy
+func (f *filler) storeLiteral(field *Field, errCount int) {
+	if field.errCount() == errCount && f.isParsedOnce(field) {
+		f.parsed.Store(literalKey{field.Value.Type(), field.Tag, f.isStrict(field)}, copyValue(field.Value))
+	}
+}
+
+// isParsedOnce tells whether the literal of the field is parsed the same way on every filling. Custom fill funcs may fill
+// elements with anything and fields set explicitly may include elements, so neither is ever cached.
+func (f *filler) isParsedOnce(field *Field) bool {
+	if f.customFuncs || field.StructField.Name == "" || !f.isPlanned(field) {
+		return false
+	}
+	root := field.root()
+	return root.state == nil || root.state.set == nil
+}
+
+// copyValue returns a deep copy of slices, arrays, maps and ptrs so a cached value never shares memory with a filled one,
+// other values including structs are copied like any assignment
+func copyValue(value reflect.Value) reflect.Value {
+	switch value.Kind() {
+	case reflect.Slice:
+		if value.IsNil() {
+			return value
+		}
+		result := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		for i := 0; i < value.Len(); i++ {
+			result.Index(i).Set(copyValue(value.Index(i)))
+		}
+		return result
+	case reflect.Array:
+		result := reflect.New(value.Type()).Elem()
+		for i := 0; i < value.Len(); i++ {
+			result.Index(i).Set(copyValue(value.Index(i)))
+		}
+		return result
+	case reflect.Map:
+		if value.IsNil() {
+			return value
+		}
+		result := reflect.MakeMapWithSize(value.Type(), value.Len())
+		for iter := value.MapRange(); iter.Next(); {
+			result.SetMapIndex(copyValue(iter.Key()), copyValue(iter.Value()))
+		}
+		return result
+	case reflect.Ptr:
+		if value.IsNil() {
+			return value
+		}
+		result := reflect.New(value.Type().Elem())
+		result.Elem().Set(copyValue(value.Elem()))
+		return result
+	default:
+		return value
+	}
+}
@@ -0,0 +1,805 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"math"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sidai/defaults"
+)
+
+const (
+	defaultTag = "default"
+	diveKey    = "dive"
+	omitKey    = "omit"
+)
+
+var errUnsupported = errors.New("unsupported type")
+
+// basicTypes maps the predeclared types to the type their literals are parsed into
+var basicTypes = map[string]reflect.Type{
+	"bool":       reflect.TypeOf(false),
+	"string":     reflect.TypeOf(""),
+	"int":        reflect.TypeOf(int(0)),
+	"int8":       reflect.TypeOf(int8(0)),
+	"int16":      reflect.TypeOf(int16(0)),
+	"int32":      reflect.TypeOf(int32(0)),
+	"rune":       reflect.TypeOf(rune(0)),
+	"int64":      reflect.TypeOf(int64(0)),
+	"uint":       reflect.TypeOf(uint(0)),
+	"uint8":      reflect.TypeOf(uint8(0)),
+	"byte":       reflect.TypeOf(byte(0)),
+	"uint16":     reflect.TypeOf(uint16(0)),
+	"uint32":     reflect.TypeOf(uint32(0)),
+	"uint64":     reflect.TypeOf(uint64(0)),
+	"uintptr":    reflect.TypeOf(uintptr(0)),
+	"float32":    reflect.TypeOf(float32(0)),
+	"float64":    reflect.TypeOf(float64(0)),
+	"complex64":  reflect.TypeOf(complex64(0)),
+	"complex128": reflect.TypeOf(complex128(0)),
+}
+
+type kind int
+
+const (
+	basicKind    kind = iota // predeclared types and local types defined from them
+	durationKind             // time.Duration
+	timeKind                 // time.Time
+	structKind               // local struct types
+	ptrKind
+	sliceKind
+	arrayKind
+	mapKind
+	nilKind // interface, func and chan types, left untouched unless zero checked
+)
+
+// typeInfo describes a field type as far as filling is concerned
+type typeInfo struct {
+	kind  kind
+	expr  string       // Go source of the type in the generated file
+	named string       // name of the local type, if any
+	rtype reflect.Type // type the tag is parsed into, nil for types holding structs
+	elem  *typeInfo
+	key   *typeInfo
+	len   int
+}
+
+// holdsStruct tells whether the type is filled struct by struct rather than parsed from a literal
+func (t *typeInfo) holdsStruct() bool {
+	switch t.kind {
+	case structKind:
+		return true
+	case nilKind:
+		return t.rtype == nil
+	case ptrKind, sliceKind, arrayKind, mapKind:
+		return t.elem.holdsStruct()
+	default:
+		return false
+	}
+}
+
+// comparable tells whether the zero value of the type can be checked by ==
+func (t *typeInfo) comparable() bool {
+	switch t.kind {
+	case basicKind, durationKind, timeKind, ptrKind:
+		return true
+	case arrayKind:
+		return t.elem.comparable()
+	default:
+		return false
+	}
+}
+
+type typeDecl struct {
+	expr    ast.Expr
+	imports map[string]string // local name -> import path of the file declaring the type
+}
+
+type generator struct {
+	decls   map[string]typeDecl
+	methods map[string]map[string]bool
+	pkg     string
+
+	types  map[string]bool     // types to generate
+	edges  map[string][]string // struct types allocated or filled by each generated type
+	zeros  []string            // struct types needing a zero check, in order of first use
+	zeroed map[string]bool
+	vars   int
+	time   bool
+
+	filler defaults.Filler
+	buf    bytes.Buffer
+}
+
+// generate returns the source of the SetDefaults methods of the types declared in the package in dir
+func generate(dir string, types []string, output string) ([]byte, error) {
+	g := &generator{
+		decls:   make(map[string]typeDecl),
+		methods: make(map[string]map[string]bool),
+		types:   make(map[string]bool),
+		edges:   make(map[string][]string),
+		zeroed:  make(map[string]bool),
+		// Same configuration as the package level filler
+		filler: defaults.NewFiller(defaults.UseDefault(), defaults.UseTimeFormat(time.RFC3339), defaults.ParseDuration()),
+	}
+	if err := g.parseDir(dir, output); err != nil {
+		return nil, err
+	}
+
+	for _, name := range types {
+		decl, ok := g.decls[name]
+		if !ok {
+			return nil, fmt.Errorf("type %s not found in %s", name, dir)
+		}
+		if _, ok := decl.expr.(*ast.StructType); !ok {
+			return nil, fmt.Errorf("type %s is not a struct", name)
+		}
+		g.types[name] = true
+	}
+
+	var body bytes.Buffer
+	for _, name := range types {
+		if err := g.genSetDefaults(name); err != nil {
+			return nil, err
+		}
+		body.Write(g.buf.Bytes())
+		g.buf.Reset()
+	}
+	for i := 0; i < len(g.zeros); i++ { // zero checks may need further zero checks
+		if err := g.genIsZero(g.zeros[i]); err != nil {
+			return nil, err
+		}
+		body.Write(g.buf.Bytes())
+		g.buf.Reset()
+	}
+	if err := g.checkCycles(types); err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(&g.buf, "// Code generated by defaults-gen; DO NOT EDIT.\n\npackage %s\n\n", g.pkg)
+	if g.time {
+		g.buf.WriteString("import \"time\"\n\n")
+	}
+	g.buf.Write(body.Bytes())
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated source: %w", err)
+	}
+	return src, nil
+}
+
+// parseDir collects the type declarations and methods of the package, except tests and the output file
+func (g *generator) parseDir(dir, output string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return err
+	}
+
+	fset := token.NewFileSet()
+	for _, path := range paths {
+		base := filepath.Base(path)
+		if strings.HasSuffix(base, "_test.go") || base == output {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		g.pkg = file.Name.Name
+
+		imports := make(map[string]string)
+		for _, spec := range file.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			name := path[strings.LastIndex(path, "/")+1:]
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			imports[name] = path
+		}
+
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					if spec, ok := spec.(*ast.TypeSpec); ok {
+						g.decls[spec.Name.Name] = typeDecl{expr: spec.Type, imports: imports}
+					}
+				}
+			case *ast.FuncDecl:
+				if decl.Recv == nil || len(decl.Recv.List) == 0 {
+					continue
+				}
+				recv := typeName(decl.Recv.List[0].Type)
+				if g.methods[recv] == nil {
+					g.methods[recv] = make(map[string]bool)
+				}
+				g.methods[recv][decl.Name.Name] = true
+			}
+		}
+	}
+
+	if g.pkg == "" {
+		return fmt.Errorf("no Go files found in %s", dir)
+	}
+	return nil
+}
+
+// typeName returns the name of a receiver or embedded field type, e.g. Config of *Config or pkg.Config
+func typeName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr.Name
+	case *ast.StarExpr:
+		return typeName(expr.X)
+	case *ast.SelectorExpr:
+		return expr.Sel.Name
+	case *ast.ParenExpr:
+		return typeName(expr.X)
+	case *ast.IndexExpr: // receiver of a generic type
+		return typeName(expr.X)
+	default:
+		return ""
+	}
+}
+
+// resolve describes the type expression found in the file with the imports
+func (g *generator) resolve(expr ast.Expr, imports map[string]string) (*typeInfo, error) {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return g.resolveIdent(expr.Name)
+	case *ast.ParenExpr:
+		return g.resolve(expr.X, imports)
+	case *ast.SelectorExpr:
+		if pkg, ok := expr.X.(*ast.Ident); ok && imports[pkg.Name] == "time" {
+			switch expr.Sel.Name {
+			case "Duration":
+				return &typeInfo{kind: durationKind, expr: "time.Duration", rtype: reflect.TypeOf(time.Duration(0))}, nil
+			case "Time":
+				return &typeInfo{kind: timeKind, expr: "time.Time", rtype: reflect.TypeOf(time.Time{})}, nil
+			}
+		}
+		return nil, fmt.Errorf("%w %s", errUnsupported, exprString(expr))
+	case *ast.StarExpr:
+		elem, err := g.resolve(expr.X, imports)
+		if err != nil {
+			return nil, err
+		}
+		info := &typeInfo{kind: ptrKind, expr: "*" + elem.expr, elem: elem}
+		if elem.rtype != nil {
+			info.rtype = reflect.PtrTo(elem.rtype)
+		}
+		return info, nil
+	case *ast.ArrayType:
+		elem, err := g.resolve(expr.Elt, imports)
+		if err != nil {
+			return nil, err
+		}
+		if expr.Len == nil {
+			info := &typeInfo{kind: sliceKind, expr: "[]" + elem.expr, elem: elem}
+			if elem.rtype != nil {
+				info.rtype = reflect.SliceOf(elem.rtype)
+			}
+			return info, nil
+		}
+
+		lit, ok := expr.Len.(*ast.BasicLit)
+		if !ok || lit.Kind != token.INT {
+			return nil, fmt.Errorf("%w %s, array length must be an integer literal", errUnsupported, exprString(expr))
+		}
+		n, err := strconv.Atoi(lit.Value)
+		if err != nil {
+			return nil, err
+		}
+		info := &typeInfo{kind: arrayKind, expr: fmt.Sprintf("[%d]%s", n, elem.expr), elem: elem, len: n}
+		if elem.rtype != nil {
+			info.rtype = reflect.ArrayOf(n, elem.rtype)
+		}
+		return info, nil
+	case *ast.MapType:
+		key, err := g.resolve(expr.Key, imports)
+		if err != nil {
+			return nil, err
+		}
+		elem, err := g.resolve(expr.Value, imports)
+		if err != nil {
+			return nil, err
+		}
+		if key.holdsStruct() {
+			return nil, fmt.Errorf("%w %s, map key must not be a struct", errUnsupported, exprString(expr))
+		}
+		info := &typeInfo{kind: mapKind, expr: fmt.Sprintf("map[%s]%s", key.expr, elem.expr), key: key, elem: elem}
+		if elem.rtype != nil {
+			info.rtype = reflect.MapOf(key.rtype, elem.rtype)
+		}
+		return info, nil
+	case *ast.InterfaceType, *ast.FuncType, *ast.ChanType:
+		info := &typeInfo{kind: nilKind, expr: exprString(expr)}
+		if _, ok := expr.(*ast.InterfaceType); !ok {
+			// func and chan are never filled, unlike interfaces holding structs
+			info.rtype = reflect.TypeOf((*func())(nil)).Elem()
+		}
+		return info, nil
+	default:
+		return nil, fmt.Errorf("%w %s", errUnsupported, exprString(expr))
+	}
+}
+
+func (g *generator) resolveIdent(name string) (*typeInfo, error) {
+	if rtype, ok := basicTypes[name]; ok {
+		return &typeInfo{kind: basicKind, expr: name, rtype: rtype}, nil
+	}
+	if name == "any" || name == "error" {
+		return &typeInfo{kind: nilKind, expr: name}, nil
+	}
+
+	decl, ok := g.decls[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %s", errUnsupported, name)
+	}
+	if _, ok := decl.expr.(*ast.StructType); ok {
+		return &typeInfo{kind: structKind, expr: name, named: name}, nil
+	}
+	// Types decoding their own text are filled by UnmarshalText rather than by their underlying kind
+	if g.methods[name]["UnmarshalText"] {
+		return nil, fmt.Errorf("%w %s, encoding.TextUnmarshaler is not supported", errUnsupported, name)
+	}
+
+	underlying, err := g.resolve(decl.expr, decl.imports)
+	if err != nil {
+		return nil, err
+	}
+	info := *underlying
+	info.expr, info.named = name, name
+	switch info.kind {
+	case durationKind:
+		// Only time.Duration itself is parsed as a duration
+		info.kind, info.rtype = basicKind, reflect.TypeOf(int64(0))
+	case timeKind, structKind:
+		return nil, fmt.Errorf("%w %s, defined from another struct", errUnsupported, name)
+	}
+	return &info, nil
+}
+
+func exprString(expr ast.Expr) string {
+	var buf bytes.Buffer
+	_ = format.Node(&buf, token.NewFileSet(), expr)
+	return buf.String()
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// newVar returns a variable name unique within the generated file
+func (g *generator) newVar(prefix string) string {
+	g.vars++
+	return fmt.Sprintf("%s%d", prefix, g.vars)
+}
+
+// fields calls fn with the selector, type and default tag of every field of the struct
+func (g *generator) fields(name string, exported bool, fn func(field string, typ ast.Expr, tag string) error) error {
+	st := g.decls[name].expr.(*ast.StructType)
+	for _, field := range st.Fields.List {
+		var tag string
+		if field.Tag != nil {
+			raw, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				return err
+			}
+			tag = reflect.StructTag(raw).Get(defaultTag)
+		}
+
+		names := []string{typeName(field.Type)} // embedded field
+		if len(field.Names) > 0 {
+			names = names[:0]
+			for _, ident := range field.Names {
+				names = append(names, ident.Name)
+			}
+		}
+
+		for _, fieldName := range names {
+			if fieldName == "_" || exported && !ast.IsExported(fieldName) {
+				continue
+			}
+			if err := fn(fieldName, field.Type, tag); err != nil {
+				return fmt.Errorf("%s.%s: %w", name, fieldName, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (g *generator) genSetDefaults(name string) error {
+	imports := g.decls[name].imports
+
+	// SetDefaults also calls ApplyDefaults of the root type, as the reflective filler does after filling the fields
+	if g.methods[name]["ApplyDefaults"] {
+		g.printf("// SetDefaults fills the zero fields of %s with their default tag, then calls ApplyDefaults ignoring its error\n", name)
+		g.printf("func (s *%s) SetDefaults() {\ns.SetGeneratedDefaults()\n_ = s.ApplyDefaults()\n}\n\n", name)
+	} else {
+		g.printf("// SetDefaults fills the zero fields of %s with their default tag\n", name)
+		g.printf("func (s *%s) SetDefaults() {\ns.SetGeneratedDefaults()\n}\n\n", name)
+	}
+
+	g.printf("// SetGeneratedDefaults fills the zero fields of %s with their default tag, without calling any defaults.Defaulter.\n", name)
+	g.printf("// defaults.SetDefaults calls it instead of filling the fields by reflection.\n")
+	g.printf("func (s *%s) SetGeneratedDefaults() {\n", name)
+	err := g.fields(name, true, func(field string, typ ast.Expr, tag string) error {
+		info, err := g.resolve(typ, imports)
+		if err != nil {
+			return err
+		}
+		if target := filledStruct(info); tag != omitKey && target != "" {
+			g.edges[name] = append(g.edges[name], target)
+		}
+		return g.genField("s."+field, info, tag)
+	})
+	g.printf("}\n\n")
+
+	return err
+}
+
+// filledStruct returns the struct type a field of the type fills even when zero, which may lead back to the type being filled.
+// Only struct and pointer fields do, directly or as elements of arrays, while slices and maps only hold existing elements.
+func filledStruct(info *typeInfo) string {
+	switch info.kind {
+	case structKind:
+		return info.named
+	case ptrKind, arrayKind:
+		return filledStruct(info.elem)
+	default:
+		return ""
+	}
+}
+
+// genField writes the statements filling the field like the reflective filler would
+func (g *generator) genField(expr string, info *typeInfo, tag string) error {
+	if !info.holdsStruct() {
+		return g.genLiteral(expr, info, tag)
+	}
+
+	switch info.kind {
+	case structKind:
+		if err := g.checkNested(info.named); err != nil {
+			return err
+		}
+		switch tag {
+		case omitKey:
+		case diveKey:
+			g.printf("%s.SetGeneratedDefaults()\n", expr)
+		default:
+			g.printf("if %s {\n%s.SetGeneratedDefaults()\n}\n", g.isZero(expr, info), expr)
+		}
+	case ptrKind:
+		if info.elem.kind != structKind {
+			return fmt.Errorf("%w %s", errUnsupported, info.expr)
+		}
+		if err := g.checkNested(info.elem.named); err != nil {
+			return err
+		}
+		switch tag {
+		case omitKey:
+			return nil
+		case diveKey:
+			g.printf("if %s != nil {\n%s.SetGeneratedDefaults()\n} else {\n", expr, expr)
+		default:
+			g.printf("if %s == nil {\n", expr)
+		}
+		// A nil pointer is only set when the allocated struct gets any default
+		v := g.newVar("v")
+		g.printf("%s := new(%s)\n%s.SetGeneratedDefaults()\n", v, info.elem.expr, v)
+		g.printf("if !%s(%s) {\n%s = %s\n}\n}\n", g.isZeroFunc(info.elem.named), v, expr, v)
+	case sliceKind:
+		// Elements of a nil slice do not exist, while others are only filled on dive
+		if tag == diveKey {
+			i := g.newVar("i")
+			g.printf("for %s := range %s {\n", i, expr)
+			if err := g.genField(expr+"["+i+"]", info.elem, tag); err != nil {
+				return err
+			}
+			g.printf("}\n")
+		}
+	case arrayKind:
+		if tag == omitKey {
+			return nil
+		}
+		if tag != diveKey {
+			tag = ""
+			g.printf("if %s {\n", g.isZero(expr, info))
+		}
+		i := g.newVar("i")
+		g.printf("for %s := range %s {\n", i, expr)
+		if err := g.genField(expr+"["+i+"]", info.elem, tag); err != nil {
+			return err
+		}
+		g.printf("}\n")
+		if tag != diveKey {
+			g.printf("}\n")
+		}
+	case mapKind:
+		if tag == diveKey {
+			k, v := g.newVar("k"), g.newVar("v")
+			g.printf("for %s, %s := range %s {\n", k, v, expr)
+			if err := g.genField(v, info.elem, tag); err != nil {
+				return err
+			}
+			g.printf("%s[%s] = %s\n}\n", expr, k, v)
+		}
+	case nilKind:
+		// Interfaces are only filled on dive by their dynamic type, which is unknown until run time
+		if tag == diveKey {
+			return fmt.Errorf("%w %s, interface can not dive", errUnsupported, info.expr)
+		}
+	}
+
+	return nil
+}
+
+// checkNested reports struct types the generated code depends on but which do not get a generated method
+func (g *generator) checkNested(name string) error {
+	if !g.types[name] {
+		return fmt.Errorf("type %s must be generated as well", name)
+	}
+	// The reflective filler calls ApplyDefaults of every nested struct, which SetDefaults does not
+	if g.methods[name]["ApplyDefaults"] {
+		return fmt.Errorf("nested type %s implements defaults.Defaulter, which is only supported by the root type", name)
+	}
+	return nil
+}
+
+// genLiteral parses the tag by the reflective filler then assigns the result when the field is zero
+func (g *generator) genLiteral(expr string, info *typeInfo, tag string) error {
+	if info.kind == nilKind {
+		return nil
+	}
+
+	typ := reflect.StructOf([]reflect.StructField{{
+		Name: "V",
+		Type: info.rtype,
+		Tag:  reflect.StructTag(defaultTag + ":" + strconv.Quote(tag)),
+	}})
+	value := reflect.New(typ)
+	if err := g.filler.SetDefaultsE(value.Interface()); err != nil {
+		var fieldErr *defaults.FieldError
+		if errors.As(err, &fieldErr) {
+			err = fieldErr.Err
+		}
+		return fmt.Errorf("invalid default %q: %w", tag, err)
+	}
+
+	result := value.Elem().Field(0)
+	if result.IsZero() {
+		return nil
+	}
+
+	lit, err := g.literal(result, info)
+	if err != nil {
+		return err
+	}
+	g.printf("if %s {\n%s = %s\n}\n", g.isZero(expr, info), expr, lit)
+	return nil
+}
+
+// literal returns the Go expression of the value of the type
+func (g *generator) literal(value reflect.Value, info *typeInfo) (string, error) {
+	switch info.kind {
+	case basicKind:
+		lit, err := basicLiteral(value)
+		if err != nil || info.named == "" {
+			return lit, err
+		}
+		return info.expr + "(" + lit + ")", nil
+	case durationKind:
+		g.time = true
+		return fmt.Sprintf("time.Duration(%d)", value.Int()), nil
+	case timeKind:
+		t := value.Interface().(time.Time)
+		if t.Location() != time.UTC {
+			return "", fmt.Errorf("%w time %s, only UTC is supported", errUnsupported, t)
+		}
+		g.time = true
+		return fmt.Sprintf("time.Date(%d, %d, %d, %d, %d, %d, %d, time.UTC)",
+			t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond()), nil
+	case ptrKind:
+		if value.IsNil() {
+			return "nil", nil
+		}
+		elem, err := g.literal(value.Elem(), info.elem)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("func() %s { v := %s; return &v }()", info.expr, elem), nil
+	case sliceKind, arrayKind:
+		if info.kind == sliceKind && value.IsNil() {
+			return "nil", nil
+		}
+		if info.kind == sliceKind && info.rtype.Elem().Kind() == reflect.Uint8 {
+			return fmt.Sprintf("%s(%s)", info.expr, strconv.Quote(string(value.Bytes()))), nil
+		}
+
+		elems := make([]string, value.Len())
+		for i := range elems {
+			elem, err := g.literal(value.Index(i), info.elem)
+			if err != nil {
+				return "", err
+			}
+			elems[i] = elem
+		}
+		return info.expr + "{" + strings.Join(elems, ", ") + "}", nil
+	case mapKind:
+		if value.IsNil() {
+			return "nil", nil
+		}
+
+		entries := make([]string, 0, value.Len())
+		for _, key := range value.MapKeys() {
+			k, err := g.literal(key, info.key)
+			if err != nil {
+				return "", err
+			}
+			v, err := g.literal(value.MapIndex(key), info.elem)
+			if err != nil {
+				return "", err
+			}
+			entries = append(entries, k+": "+v)
+		}
+		sort.Strings(entries)
+		return info.expr + "{" + strings.Join(entries, ", ") + "}", nil
+	default:
+		return "", fmt.Errorf("%w %s", errUnsupported, info.expr)
+	}
+}
+
+func basicLiteral(value reflect.Value) (string, error) {
+	switch value.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), nil
+	case reflect.String:
+		return strconv.Quote(value.String()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(value.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return floatLiteral(value.Float(), value.Type().Bits())
+	case reflect.Complex64, reflect.Complex128:
+		c := value.Complex()
+		re, err := floatLiteral(real(c), value.Type().Bits()/2)
+		if err != nil {
+			return "", err
+		}
+		im, err := floatLiteral(imag(c), value.Type().Bits()/2)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("complex(%s, %s)", re, im), nil
+	default:
+		return "", fmt.Errorf("%w %s", errUnsupported, value.Type())
+	}
+}
+
+func floatLiteral(f float64, bits int) (string, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return "", fmt.Errorf("%w value %v", errUnsupported, f)
+	}
+	return strconv.FormatFloat(f, 'g', -1, bits), nil
+}
+
+// isZero returns the condition of the expression holding the zero value of the type, like reflect.Value.IsZero
+func (g *generator) isZero(expr string, info *typeInfo) string {
+	switch info.kind {
+	case basicKind, durationKind:
+		switch info.rtype.Kind() {
+		case reflect.Bool:
+			return "!" + expr
+		case reflect.String:
+			return expr + ` == ""`
+		default:
+			return expr + " == 0"
+		}
+	case timeKind:
+		g.time = true
+		return expr + " == (time.Time{})"
+	case structKind:
+		return g.isZeroFunc(info.named) + "(&" + expr + ")"
+	case arrayKind:
+		if info.elem.comparable() {
+			return expr + " == (" + info.expr + "{})"
+		}
+		conds := make([]string, info.len)
+		for i := range conds {
+			conds[i] = g.isZero(fmt.Sprintf("%s[%d]", expr, i), info.elem)
+		}
+		if len(conds) == 0 {
+			return "true"
+		}
+		return strings.Join(conds, " && ")
+	default:
+		return expr + " == nil"
+	}
+}
+
+// isZeroFunc returns the name of the function checking the struct type, which is generated once needed
+func (g *generator) isZeroFunc(name string) string {
+	if !g.zeroed[name] {
+		g.zeroed[name] = true
+		g.zeros = append(g.zeros, name)
+	}
+	return "isZero" + name
+}
+
+func (g *generator) genIsZero(name string) error {
+	imports := g.decls[name].imports
+
+	var conds []string
+	err := g.fields(name, false, func(field string, typ ast.Expr, _ string) error {
+		info, err := g.resolve(typ, imports)
+		if err != nil {
+			return err
+		}
+		if info.kind == structKind && !g.types[info.named] {
+			return fmt.Errorf("type %s must be generated as well", info.named)
+		}
+		conds = append(conds, g.isZero("v."+field, info))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(conds) == 0 {
+		conds = append(conds, "true")
+	}
+
+	g.printf("func %s(v *%s) bool {\nreturn %s\n}\n\n", g.isZeroFunc(name), name, strings.Join(conds, " &&\n"))
+	return nil
+}
+
+// checkCycles rejects types allocating themselves through pointers, which would fill forever
+func (g *generator) checkCycles(types []string) error {
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make(map[string]int)
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("self-referential type %s, add `default:\"omit\"` to break the cycle",
+				strings.Join(append(path, name), " > "))
+		case visited:
+			return nil
+		}
+
+		state[name] = visiting
+		for _, next := range g.edges[name] {
+			if err := visit(next, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+
+	for _, name := range types {
+		if err := visit(name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
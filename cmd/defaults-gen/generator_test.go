@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type GeneratorSuite struct{}
+
+var _ = Suite(&GeneratorSuite{})
+
+func (s *GeneratorSuite) TestUpToDate(c *C) {
+	dir := filepath.Join("..", "..", "internal", "gentest")
+	expected, err := os.ReadFile(filepath.Join(dir, "config_defaults.go"))
+	c.Assert(err, IsNil)
+
+	src, err := generate(dir, []string{"Config", "Server", "TLS", "Limit"}, "config_defaults.go")
+
+	c.Assert(err, IsNil)
+	c.Assert(string(src), Equals, string(expected), Commentf("run go generate ./internal/gentest"))
+}
+
+func (s *GeneratorSuite) TestErrors(c *C) {
+	for _, t := range []struct {
+		src   string
+		types []string
+		err   string
+	}{
+		{"type T struct{ A int `default:\"a\"` }", []string{"T"}, `T.A: invalid default "a": .*invalid syntax`},
+		{"type T struct{ A []int `default:\"[a]\"` }", []string{"T"}, `T.A: invalid default "\[a\]": .*invalid syntax`},
+		{"type T struct{ A S }\ntype S struct{}", []string{"T"}, `T.A: type S must be generated as well`},
+		{"type T struct{ Next *T }", []string{"T"}, `self-referential type T > T, .*`},
+		{"type T struct{ S *S }\ntype S struct{ T *T }", []string{"T", "S"}, `self-referential type T > S > T, .*`},
+		{"type T struct{ Kids [2]*T; V int `default:\"1\"` }", []string{"T"}, `self-referential type T > T, .*`},
+		{"type T struct{ Kids [2][1]*T }", []string{"T"}, `self-referential type T > T, .*`},
+		{"type T struct{ S [1]S }\ntype S struct{ T *T }", []string{"T", "S"}, `self-referential type T > S > T, .*`},
+		{"type T struct{ A interface{} `default:\"dive\"` }", []string{"T"}, `T.A: unsupported type interface\{\}, interface can not dive`},
+		{"type T struct{ A **T }", []string{"T"}, `T.A: unsupported type \*\*T`},
+		{"type T struct{ A json.Number }", []string{"T"}, `T.A: unsupported type json.Number`},
+		{"type T struct{ A S }\ntype S struct{}\nfunc (*S) ApplyDefaults() error { return nil }", []string{"T", "S"}, `T.A: nested type S implements defaults.Defaulter, .*`},
+		{"type T int", []string{"T"}, `type T is not a struct`},
+		{"type T struct{}", []string{"U"}, `type U not found in .*`},
+	} {
+		dir := c.MkDir()
+		src := "package p\n\nimport \"encoding/json\"\n\nvar _ json.Number\n\n" + t.src + "\n"
+		c.Assert(os.WriteFile(filepath.Join(dir, "p.go"), []byte(src), 0o644), IsNil)
+
+		_, err := generate(dir, t.types, "p_defaults.go")
+		c.Assert(err, ErrorMatches, t.err, Commentf(t.src))
+	}
+}
+
+func (s *GeneratorSuite) TestOutputName(c *C) {
+	c.Assert(outputName("config.go", "Config"), Equals, "config_defaults.go")
+	c.Assert(outputName("", "Server"), Equals, "server_defaults.go")
+}
@@ -0,0 +1,66 @@
+// Command defaults-gen generates reflection-free SetDefaults methods from the default tags of struct types.
+//
+// Tags are parsed at generation time by the same filler as the package level defaults.SetDefaults,
+// the generated methods only hold the resulting literal assignments, e.g.
+//
+//	//go:generate go run github.com/sidai/defaults/cmd/defaults-gen -type Config,Server
+//
+// writes config_defaults.go next to the file declaring the go:generate directive.
+// Any struct type filled from a generated type, by value or by pointer, must be generated as well.
+// Every type gets a SetGeneratedDefaults method, which defaults.SetDefaults calls instead of filling by reflection,
+// and a SetDefaults method calling it then ApplyDefaults when the type implements defaults.Defaulter.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("defaults-gen: ")
+
+	typeNames := flag.String("type", "", "comma-separated list of struct type names, required")
+	output := flag.String("output", "", "output file name, default <file>_defaults.go of $GOFILE or the first type")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: defaults-gen -type T[,T...] [-output file] [directory]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	types := strings.Split(*typeNames, ",")
+	name := *output
+	if name == "" {
+		name = outputName(os.Getenv("GOFILE"), types[0])
+	}
+
+	src, err := generate(dir, types, name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// outputName names the generated file after the file running go generate, or after the type otherwise
+func outputName(goFile, typeName string) string {
+	if goFile != "" {
+		return strings.TrimSuffix(goFile, ".go") + "_defaults.go"
+	}
+	return strings.ToLower(typeName) + "_defaults.go"
+}
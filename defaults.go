@@ -1,59 +1,137 @@
 package defaults
 
 import (
+	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 var (
-	defaultFiller *filler
+	// defaultFiller holds the *filler built from defaultOpts, it is replaced rather than modified on every configuration
+	// so filling never races with registration
+	defaultFiller atomic.Value
+	defaultOpts   []keyedOption
+	mu            sync.Mutex
 	once          sync.Once
 )
 
+// keyedOption is an option of the package level filler, a later option with the same key replaces it
+type keyedOption struct {
+	key interface{}
+	opt Option
+}
+
+type (
+	defaultTagOption struct{}
+	omitKeyOption    struct{}
+	diveKeyOption    struct{}
+)
+
 func SetDefaults(variable interface{}) {
 	GetDefaultFiller().SetDefaults(variable)
 }
 
+// SetDefaultsE works like SetDefaults but reports every default value that could not be applied
+func SetDefaultsE(variable interface{}) error {
+	return GetDefaultFiller().SetDefaultsE(variable)
+}
+
+// SetDefaultsWithReport works like SetDefaultsE and also reports every field changed together with its source
+func SetDefaultsWithReport(variable interface{}) (Report, error) {
+	return GetDefaultFiller().SetDefaultsWithReport(variable)
+}
+
+// FillUnset works like SetDefaultsE but keeps the value of every field in set, even a zero one
+func FillUnset(variable interface{}, set FieldSet) error {
+	return GetDefaultFiller().FillUnset(variable, set)
+}
+
 type Filler interface {
 	SetDefaults(variable interface{})
+	// SetDefaultsE returns ErrInvalidVariable or Errors of FieldError when any default value fails to apply
+	SetDefaultsE(variable interface{}) error
+	// SetDefaultsWithReport returns the changed fields in addition to the errors of SetDefaultsE
+	SetDefaultsWithReport(variable interface{}) (Report, error)
+	// FillUnset only fills the fields not in set, and struct fields are filled whether they are empty or not
+	FillUnset(variable interface{}, set FieldSet) error
 }
 
 func NewFiller(opts ...Option) Filler {
 	return newFiller(opts...)
 }
 
+// GetDefaultFiller returns the current package level filler,
+// it is not affected by any configuration made after it is returned
 func GetDefaultFiller() Filler {
 	initDefaultFiller()
-	return defaultFiller
+	return defaultFiller.Load().(*filler)
 }
 
 func SetDefaultTag(tag string) {
-	initDefaultFiller()
-	UseDefaultTag(tag)(defaultFiller)
+	configureDefaultFiller(defaultTagOption{}, UseDefaultTag(tag))
 }
 
 func SetOmitKey(key string) {
-	initDefaultFiller()
-	UseOmitKey(key)(defaultFiller)
+	configureDefaultFiller(omitKeyOption{}, UseOmitKey(key))
 }
 
 func SetDiveKey(key string) {
-	initDefaultFiller()
-	UseDiveKey(key)(defaultFiller)
+	configureDefaultFiller(diveKeyOption{}, UseDiveKey(key))
 }
 
 func RegisterDefaultType(defVal interface{}) {
-	initDefaultFiller()
-	UseDefaultType(defVal)(defaultFiller)
+	configureDefaultFiller(reflect.TypeOf(defVal), UseDefaultType(defVal))
 }
 
 func RegisterTimeLayout(layout string) {
+	configureDefaultFiller(timeType, UseTimeFormat(layout))
+}
+
+// configureDefaultFiller rebuilds the package level filler with the option in place of any earlier one with the same key,
+// a copy-on-write of its configuration. The configuration only grows with the keys, not with every call, and the plans
+// are kept as long as the default tag is.
+func configureDefaultFiller(key interface{}, opt Option) {
 	initDefaultFiller()
-	UseTimeFormat(layout)(defaultFiller)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	opts := make([]keyedOption, 0, len(defaultOpts)+1)
+	for _, o := range defaultOpts {
+		if o.key != key {
+			opts = append(opts, o)
+		}
+	}
+	// The option goes last even when it replaces one, options of the same key apply in the order they are configured
+	defaultOpts = append(opts, keyedOption{key: key, opt: opt})
+
+	current := defaultFiller.Load().(*filler)
+	next := newFiller(unkeyed(defaultOpts)...)
+	if next.DefaultTag == current.DefaultTag {
+		next.cache = current.cache
+	}
+	defaultFiller.Store(next)
 }
 
 func initDefaultFiller() {
 	once.Do(func() {
-		defaultFiller = newFiller(UseDefault(), UseTimeFormat(time.RFC3339), ParseDuration())
+		for _, opt := range packageOptions() {
+			defaultOpts = append(defaultOpts, keyedOption{opt: opt})
+		}
+		defaultFiller.Store(newFiller(unkeyed(defaultOpts)...))
 	})
-}
\ No newline at end of file
+}
+
+func unkeyed(opts []keyedOption) []Option {
+	result := make([]Option, len(opts))
+	for i, o := range opts {
+		result[i] = o.opt
+	}
+	return result
+}
+
+// packageOptions returns the initial configuration of the package level filler, which cmd/defaults-gen generates code for
+func packageOptions() []Option {
+	return []Option{UseDefault(), UseTimeFormat(time.RFC3339), ParseDuration()}
+}
@@ -0,0 +1,81 @@
+package defaults
+
+import (
+	. "gopkg.in/check.v1"
+	"sync"
+	"time"
+)
+
+type DefaultsSuite struct{}
+
+var _ = Suite(&DefaultsSuite{})
+
+type Concurrent string
+
+type ExampleConcurrent struct {
+	Int        int `default:"1"`
+	Concurrent Concurrent
+	Time       time.Time `default:"2007-07-07T07:07:07.007Z"`
+}
+
+func (s *DefaultsSuite) TestConcurrentConfiguration(c *C) {
+	var wg sync.WaitGroup
+	results := make([]ExampleConcurrent, 32)
+	for i := range results {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			RegisterDefaultType(Concurrent("concurrent"))
+			RegisterTimeLayout(time.RFC3339)
+		}()
+		go func(i int) {
+			defer wg.Done()
+			SetDefaults(&results[i])
+		}(i)
+	}
+	wg.Wait()
+
+	expectedTime, _ := time.Parse(time.RFC3339, "2007-07-07T07:07:07.007Z")
+	for _, result := range results {
+		c.Assert(result.Int, Equals, 1)
+		c.Assert(result.Time, DeepEquals, expectedTime)
+		// registration is either fully visible or not at all
+		c.Assert(result.Concurrent == "" || result.Concurrent == "concurrent", Equals, true)
+	}
+
+	var foo ExampleConcurrent
+	SetDefaults(&foo)
+	c.Assert(foo.Concurrent, Equals, Concurrent("concurrent"))
+}
+
+func (s *DefaultsSuite) TestGetDefaultFillerSnapshot(c *C) {
+	before := GetDefaultFiller()
+	RegisterDefaultType(Concurrent("snapshot"))
+	defer RegisterDefaultType(Concurrent("concurrent"))
+
+	var foo, bar ExampleConcurrent
+	before.SetDefaults(&foo)
+	GetDefaultFiller().SetDefaults(&bar)
+
+	c.Assert(foo.Concurrent, Not(Equals), Concurrent("snapshot"))
+	c.Assert(bar.Concurrent, Equals, Concurrent("snapshot"))
+}
+
+func (s *DefaultsSuite) TestConfigurationReplacesOptions(c *C) {
+	RegisterDefaultType(Concurrent("concurrent"))
+	before := GetDefaultFiller().(*filler)
+	count := len(defaultOpts)
+
+	for i := 0; i < 8; i++ {
+		RegisterDefaultType(Concurrent("concurrent"))
+		RegisterTimeLayout(time.RFC3339)
+	}
+
+	// repeated configuration neither grows the options nor drops the plans
+	c.Assert(len(defaultOpts) <= count+1, Equals, true)
+	c.Assert(GetDefaultFiller().(*filler).cache, Equals, before.cache)
+
+	var foo ExampleConcurrent
+	SetDefaults(&foo)
+	c.Assert(foo.Concurrent, Equals, Concurrent("concurrent"))
+}
@@ -0,0 +1,81 @@
+package defaults
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	// ErrInvalidVariable is returned when the variable to fill is not a non-nil pointer to a struct
+	ErrInvalidVariable = errors.New("defaults: variable must be a non-nil pointer to struct")
+	// ErrMalformedLiteral is reported in strict mode when a slice or map literal can not be parsed
+	ErrMalformedLiteral = errors.New("malformed literal")
+	// ErrDuplicateKey is reported in strict mode when a map literal repeats the same key
+	ErrDuplicateKey = errors.New("duplicate map key")
+	// ErrMalformedEntry is returned by ParseKeyValues for a line which is not a key value pair
+	ErrMalformedEntry = errors.New("malformed entry")
+)
+
+// FieldError describes a default value that could not be applied to a field
+type FieldError struct {
+	Path string
+	Type reflect.Type
+	Tag  string
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	var b strings.Builder
+	if e.Path != "" {
+		b.WriteString(e.Path + " ")
+	}
+	fmt.Fprintf(&b, "(%s): ", e.Type)
+	if e.Tag != "" {
+		fmt.Fprintf(&b, "invalid default %q: ", e.Tag)
+	}
+	b.WriteString(e.Err.Error())
+
+	return b.String()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Errors collects every error found while filling a single variable
+type Errors []error
+
+func (errs Errors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+
+	return "defaults: " + strings.Join(msgs, "; ")
+}
+
+func (errs Errors) Unwrap() []error {
+	return errs
+}
+
+// Is reports whether any of the errors matches target, errors.Is only follows Unwrap() []error from Go 1.20 on
+func (errs Errors) Is(target error) bool {
+	for _, err := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors matching target, errors.As only follows Unwrap() []error from Go 1.20 on
+func (errs Errors) As(target interface{}) bool {
+	for _, err := range errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
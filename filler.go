@@ -1,7 +1,14 @@
 package defaults
 
 import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"os"
 	"reflect"
+	"strings"
+	"sync"
+	"time"
 )
 
 const (
@@ -9,31 +16,166 @@ const (
 
 	diveKey = "dive"
 	omitKey = "omit"
+
+	jsonPrefix = "json:"
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	defaulterType       = reflect.TypeOf((*Defaulter)(nil)).Elem()
+	generatedType       = reflect.TypeOf((*generatedDefaulter)(nil)).Elem()
+	fileModeType        = reflect.TypeOf(os.FileMode(0))
 )
 
 type FillFn func(field *Field)
 
 type filler struct {
-	FuncsByKind map[reflect.Kind]FillFn
-	FuncsByType map[reflect.Type]FillFn
-	DefaultTag  string
-	DiveKey     string
-	OmitKey     string
+	FuncsByKind   map[reflect.Kind]FillFn
+	FuncsByType   map[reflect.Type]FillFn
+	ParsersByType map[reflect.Type]FillFn
+	DefaultTag    string
+	DiveKey       string
+	OmitKey       string
+	Strict        bool
+	MaxDepth      int
+	EnvTag        string
+	EnvPrefix     string
+	Lookup        LookupFn
+	Values        map[string]string // values by lowercase key taking precedence over default tags
+
+	TextUnmarshaler bool
+	JSON            bool
+	IgnoreGenerated bool
+	ByteSizes       bool
+
+	cache    *cache   // shared by the package level fillers of the same default tag
+	parsed   sync.Map // literalKey -> reflect.Value parsed by the fill funcs of this filler only
+	loadErrs Errors   // errors loading the values, reported by every filling
+
+	timeLayout  string // layout of UseTimeFormat
+	durations   bool   // ParseDuration is applied
+	customFuncs bool   // fill funcs are registered which generated methods know nothing about
+}
+
+// Defaulter is implemented by types computing defaults that tags can not express, e.g. a field depending on another.
+// ApplyDefaults is called every time the struct is filled, right after all its fields are filled,
+// so nested structs always complete before their parents.
+type Defaulter interface {
+	ApplyDefaults() error
+}
+
+// generatedDefaulter is implemented by the types cmd/defaults-gen generates methods for. SetGeneratedDefaults is only ever
+// written by the generator, so a hand-written SetDefaults, e.g. one calling defaults.SetDefaults, is never taken for it.
+type generatedDefaulter interface {
+	SetGeneratedDefaults()
 }
 
 type Field struct {
 	Value  reflect.Value
 	Tag    string
 	Parent *Field
+
+	// StructField is the struct field holding the value when Parent is a struct
+	StructField reflect.StructField
+	// Index is the position of the element when Parent is a slice or an array
+	Index int
+	// Key is the key of the element when Parent is a map, it is invalid for the key itself
+	Key reflect.Value
+
+	segment string
+	state   *fillState
+	nilPtr  interface{} // the nil ptr at the struct field level the value is allocated for, reported as previous value
+}
+
+// fillState holds everything collected during a single filling
+type fillState struct {
+	errs      Errors
+	reporting bool
+	report    Report
+	set       FieldSet // fields set explicitly, nil unless filling by FillUnset
+	strict    bool     // malformed literals are reported regardless of UseStrict
+}
+
+// AddError records an error against the field, it is reported by SetDefaultsE together with the field path
+func (field *Field) AddError(err error) {
+	if root := field.root(); root.state != nil {
+		root.state.errs = append(root.state.errs, &FieldError{
+			Path: field.Path(),
+			Type: field.Value.Type(),
+			Tag:  field.Tag,
+			Err:  err,
+		})
+	}
+}
+
+// errCount returns the number of errors reported so far while filling the root of the field
+func (field *Field) errCount() int {
+	if root := field.root(); root.state != nil {
+		return len(root.state.errs)
+	}
+	return 0
+}
+
+func (field *Field) root() *Field {
+	root := field
+	for root.Parent != nil {
+		root = root.Parent
+	}
+
+	return root
+}
+
+// Path returns the path of the field from the root struct, e.g. Servers[2].TLS.CertFile or Limits["api"]
+func (field *Field) Path() string {
+	var segments []string
+	for f := field; f != nil; f = f.Parent {
+		if f.segment != "" {
+			segments = append(segments, f.segment)
+		}
+	}
+
+	var b strings.Builder
+	for i := len(segments) - 1; i >= 0; i-- {
+		if b.Len() > 0 && !strings.HasPrefix(segments[i], "[") {
+			b.WriteByte('.')
+		}
+		b.WriteString(segments[i])
+	}
+
+	return b.String()
+}
+
+// elem returns the field of the value referenced by a ptr or interface field, it takes the place of the field
+func (field *Field) elem(value reflect.Value) *Field {
+	return &Field{
+		Value:       value,
+		Tag:         field.Tag,
+		Parent:      field.Parent,
+		StructField: field.StructField,
+		Index:       field.Index,
+		Key:         field.Key,
+		segment:     field.segment,
+		nilPtr:      field.nilPtr,
+	}
+}
+
+func indexSegment(index reflect.Value) string {
+	if index.Kind() == reflect.String {
+		return fmt.Sprintf("[%q]", index.Interface())
+	}
+	return fmt.Sprintf("[%v]", index.Interface())
 }
 
 func newFiller(opts ...Option) *filler {
 	f := &filler{
-		FuncsByKind: make(map[reflect.Kind]FillFn),
-		FuncsByType: make(map[reflect.Type]FillFn),
-		DefaultTag:  defaultTag,
-		DiveKey:     diveKey,
-		OmitKey:     omitKey,
+		FuncsByKind:   make(map[reflect.Kind]FillFn),
+		FuncsByType:   make(map[reflect.Type]FillFn),
+		ParsersByType: make(map[reflect.Type]FillFn),
+		DefaultTag:    defaultTag,
+		DiveKey:       diveKey,
+		OmitKey:       omitKey,
+		cache:         &cache{},
 	}
 
 	for _, opt := range opts {
@@ -44,48 +186,222 @@ func newFiller(opts ...Option) *filler {
 }
 
 func (f *filler) SetDefaults(variable interface{}) {
+	_ = f.SetDefaultsE(variable)
+}
+
+func (f *filler) SetDefaultsE(variable interface{}) error {
+	return f.fill(variable, &fillState{})
+}
+
+func (f *filler) SetDefaultsWithReport(variable interface{}) (Report, error) {
+	state := &fillState{reporting: true}
+	err := f.fill(variable, state)
+	return state.report, err
+}
+
+func (f *filler) FillUnset(variable interface{}, set FieldSet) error {
+	if set == nil {
+		set = FieldSet{}
+	}
+	return f.fill(variable, &fillState{set: set})
+}
+
+func (f *filler) fill(variable interface{}, state *fillState) error {
 	value := reflect.ValueOf(variable)
 
-	// skip if variable is not a ptr to a struct
-	if value.Kind() != reflect.Ptr || GetValueInternalKind(value) != reflect.Struct {
-		return
+	// reject if variable is not a ptr to a struct
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return ErrInvalidVariable
 	}
 
 	f.fillStruct(&Field{
 		Value:  value.Elem(),
 		Tag:    "",
 		Parent: nil,
+		state:  state,
 	})
+
+	if len(f.loadErrs) > 0 {
+		state.errs = append(append(Errors{}, f.loadErrs...), state.errs...)
+	}
+	if len(state.errs) > 0 {
+		return state.errs
+	}
+	return nil
 }
 
 func (f *filler) fillStruct(field *Field) {
-	structVal, structType := field.Value, field.Value.Type()
-
-	for i := 0; i < structVal.NumField(); i++ {
-		fieldVal, fieldType := structVal.Field(i), structType.Field(i)
-		// Only fill the filed if the field can be set, i.e. exported
-		if fieldVal.CanSet() {
-			f.fillField(&Field{
-				Value:  fieldVal,
-				Tag:    fieldType.Tag.Get(f.DefaultTag),
-				Parent: field,
-			})
+	plan := f.structPlan(field.Value.Type())
+
+	// Generated method assigns the very same defaults without reflection
+	if plan.generated && f.useGenerated(field) {
+		field.Value.Addr().Interface().(generatedDefaulter).SetGeneratedDefaults()
+	} else {
+		for _, fieldPlan := range plan.fields {
+			fieldVal := field.Value.Field(fieldPlan.index)
+			// Skip the field of an unaddressable struct, e.g. struct value stored in interface
+			if fieldVal.CanSet() {
+				child := &Field{
+					Value:       fieldVal,
+					Parent:      field,
+					StructField: fieldPlan.field,
+					segment:     fieldPlan.field.Name,
+				}
+				child.Tag = f.lookupTag(child, fieldPlan.tag)
+				f.fillField(child)
+			}
 		}
 	}
+
+	// Compute the rest of defaults once all fields are filled, errors are reported against the struct regardless of its tag
+	if plan.defaulter && field.Value.CanAddr() {
+		if defaulter, ok := field.Value.Addr().Interface().(Defaulter); ok {
+			if err := defaulter.ApplyDefaults(); err != nil {
+				untagged := field.elem(field.Value)
+				untagged.Tag, untagged.state = "", field.state
+				untagged.AddError(err)
+			}
+		}
+	}
+}
+
+// useGenerated tells whether the generated method can replace filling the struct field by field,
+// which only holds for the configuration the generator assumes and when nothing needs to be reported per field
+func (f *filler) useGenerated(field *Field) bool {
+	if f.IgnoreGenerated || !field.Value.CanAddr() {
+		return false
+	}
+	if root := field.root(); root.state != nil && (root.state.reporting || root.state.set != nil) {
+		return false
+	}
+
+	return f.matchesGenerator()
+}
+
+// matchesGenerator tells whether the filler parses every tag like cmd/defaults-gen does,
+// that is UseDefault, UseTimeFormat(time.RFC3339) and ParseDuration without any other option changing the result
+func (f *filler) matchesGenerator() bool {
+	if _, ok := f.FuncsByKind[reflect.Struct]; !ok || !f.TextUnmarshaler {
+		return false
+	}
+
+	return f.DefaultTag == defaultTag && f.DiveKey == diveKey && f.OmitKey == omitKey &&
+		f.timeLayout == time.RFC3339 && f.durations && !f.customFuncs && len(f.ParsersByType) == 0 &&
+		f.EnvTag == "" && f.Lookup == nil && f.Values == nil && !f.Strict && !f.JSON && !f.ByteSizes && f.MaxDepth == 0
+}
+
+// lookupTag returns the value to fill the struct field with, the environment variable takes precedence over loaded values
+// which take precedence over default tag. Neither applies to fields holding structs, their tag is only a dive or omit key. Variables in default tag are expanded when interpolation is enabled.
+func (f *filler) lookupTag(field *Field, tag string) string {
+	// The tag of a field holding structs only tells whether to dive or omit, which no variable or value overrides
+	holdsStructs := f.isStructContainer(field)
+
+	if f.EnvTag != "" && field.StructField.Tag.Get(f.EnvTag) != "" && !holdsStructs {
+		if value, ok := os.LookupEnv(f.envName(field)); ok {
+			return value
+		}
+	}
+
+	if f.Values != nil && !holdsStructs {
+		if value, ok := f.lookupValue(field); ok {
+			return value
+		}
+	}
+
+	if f.Lookup != nil {
+		expanded, err := interpolate(tag, f.Lookup)
+		if err != nil {
+			field.Tag = tag
+			field.AddError(err)
+			return ""
+		}
+		tag = expanded
+	}
+
+	return tag
+}
+
+// envName joins the env tags of the field and its parents, e.g. `env:"DB_"` > `env:"HOST"` gives DB_HOST
+func (f *filler) envName(field *Field) string {
+	var name string
+	for p := field; p != nil; p = p.Parent {
+		name = p.StructField.Tag.Get(f.EnvTag) + name
+	}
+
+	return f.EnvPrefix + name
 }
 
 func (f *filler) fillField(field *Field) {
+	// JSON tag decodes the entire field at once
+	if f.JSON && strings.HasPrefix(field.Tag, jsonPrefix) {
+		if f.shouldReplace(field) {
+			f.apply(field, f.unmarshalJSON, SourceTag)
+		}
+		return
+	}
+
+	// Types with their own parser take precedence over Kind, then types decoding their own text
+	// unless a fill func is registered for the exact type
+	if fn, ok := f.ParsersByType[field.Value.Type()]; ok {
+		if f.shouldReplace(field) {
+			f.apply(field, fn, SourceTag)
+		}
+		return
+	}
+
+	if f.isTextUnmarshaler(field) {
+		if f.shouldReplace(field) {
+			f.apply(field, f.unmarshalText, SourceTag)
+		}
+		return
+	}
+
 	// Fill the field when field should be filled in precedence of Kind (via Tag) > Type (via Type Default)
 	if fn, ok := f.FuncsByKind[field.Value.Kind()]; ok && f.shouldFill(field) {
-		fn(field)
+		f.apply(field, fn, SourceTag)
+	}
+
+	if fn, ok := f.FuncsByType[field.Value.Type()]; ok && f.shouldReplace(field) {
+		f.apply(field, fn, SourceType)
+	}
+}
+
+func (f *filler) isTextUnmarshaler(field *Field) bool {
+	if !f.TextUnmarshaler || field.Tag == "" || field.Tag == f.DiveKey || field.Tag == f.OmitKey || !field.Value.CanAddr() {
+		return false
+	}
+	if _, ok := f.FuncsByType[field.Value.Type()]; ok {
+		return false
 	}
 
-	if fn, ok := f.FuncsByType[field.Value.Type()]; ok && f.shouldFill(field) {
-		fn(field)
+	return reflect.PtrTo(field.Value.Type()).Implements(textUnmarshalerType)
+}
+
+func (f *filler) unmarshalText(field *Field) {
+	value := reflect.New(field.Value.Type())
+	if err := value.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(field.Tag)); err != nil {
+		field.AddError(err)
+		return
+	}
+	field.Value.Set(value.Elem())
+}
+
+func (f *filler) unmarshalJSON(field *Field) {
+	value := reflect.New(field.Value.Type())
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(field.Tag, jsonPrefix)), value.Interface()); err != nil {
+		field.AddError(err)
+		return
 	}
+	field.Value.Set(value.Elem())
 }
 
 func (f *filler) shouldFill(field *Field) bool {
+	known, set := f.presence(field)
+	// explicitly set field is kept as is, unless it holds structs which may still have unset fields
+	if set {
+		return field.Tag != f.OmitKey && f.isDescendable(field)
+	}
+
 	switch GetValueInternalKind(field.Value) {
 	case reflect.Struct:
 		// always fill struct if dive key found
@@ -96,6 +412,10 @@ func (f *filler) shouldFill(field *Field) bool {
 		if field.Tag == f.OmitKey {
 			return false
 		}
+		// struct may have unset fields even if it is not empty when presence is known
+		if known {
+			return true
+		}
 		// otherwise only fill struct if all its field is of zero value
 		return field.Value.IsZero()
 	case reflect.Interface:
@@ -107,6 +427,31 @@ func (f *filler) shouldFill(field *Field) bool {
 	}
 }
 
+// shouldReplace works like shouldFill for fill funcs setting the whole value at once, which never applies to a set field
+func (f *filler) shouldReplace(field *Field) bool {
+	return !f.isSet(field) && f.shouldFill(field)
+}
+
+// isCycle reports whether filling through the ptr field revisits data on the Field.Parent chain.
+// A non-nil ptr is a cycle when it points to any value being filled, while a nil ptr is a cycle
+// when its type is found on the chain more than MaxDepth times.
+func (f *filler) isCycle(field *Field) bool {
+	elemType := field.Value.Type().Elem()
+
+	var depth int
+	for p := field.Parent; p != nil; p = p.Parent {
+		if p.Value.Type() != elemType {
+			continue
+		}
+		if !field.Value.IsNil() && p.Value.CanAddr() && p.Value.Addr().Pointer() == field.Value.Pointer() {
+			return true
+		}
+		depth++
+	}
+
+	return field.Value.IsNil() && depth > f.MaxDepth
+}
+
 // GetValueInternalKind returns the actual underlying kind of field value.
 // It repeatedly dives into slice, array, interface and pointer kind data until find a struct,
 // unimplemented interface or a primitive data kind
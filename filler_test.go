@@ -1,8 +1,14 @@
 package defaults
 
 import (
+	"errors"
 	. "gopkg.in/check.v1"
+	"net/url"
+	"os"
 	"reflect"
+	"sort"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 )
@@ -51,18 +57,27 @@ type ExampleBasic struct {
 	IntegerVal IntegerVal `default:"1"`
 
 	UintNoTag   uint
-	UintInvalid uint   `default:"invalid"`
-	Uint        uint   `default:"1"`
-	Uint8       uint8  `default:"8"`
-	Uint16      uint16 `default:"16"`
-	Uint32      uint32 `default:"32"`
-	Uint64      uint64 `default:"64"`
+	UintInvalid uint    `default:"invalid"`
+	Uint        uint    `default:"1"`
+	Uint8       uint8   `default:"8"`
+	Uint16      uint16  `default:"16"`
+	Uint32      uint32  `default:"32"`
+	Uint64      uint64  `default:"64"`
+	Uintptr     uintptr `default:"128"`
 
 	Float32NoTag   float32
 	Float32Invalid float32 `default:"invalid"`
 	Float32        float32 `default:"0.32"`
 	Float64        float64 `default:"0.64"`
 
+	Complex64NoTag   complex64
+	Complex64Invalid complex64             `default:"invalid"`
+	Complex64        complex64             `default:"1+2i"`
+	Complex128       complex128            `default:"(-0.5-1.5i)"`
+	ComplexList      []complex128          `default:"[1, 2i, 3+4i]"`
+	ComplexMap       map[string]complex128 `default:"{re:1.5, im:-2i}"`
+	UintptrList      []uintptr             `default:"[1,2]"`
+
 	StringNoTag string
 	String      string    `default:"string"`
 	StringVal   StringVal `default:"string"`
@@ -148,12 +163,21 @@ func (s *FillerSuite) TestSetDefaults(c *C) {
 	c.Assert(foo.Uint16, Equals, uint16(16))
 	c.Assert(foo.Uint32, Equals, uint32(32))
 	c.Assert(foo.Uint64, Equals, uint64(64))
+	c.Assert(foo.Uintptr, Equals, uintptr(128))
 
 	c.Assert(foo.Float32NoTag, Equals, float32(0))
 	c.Assert(foo.Float32Invalid, Equals, float32(0))
 	c.Assert(foo.Float32, Equals, float32(0.32))
 	c.Assert(foo.Float64, Equals, 0.64)
 
+	c.Assert(foo.Complex64NoTag, Equals, complex64(0))
+	c.Assert(foo.Complex64Invalid, Equals, complex64(0))
+	c.Assert(foo.Complex64, Equals, complex64(1+2i))
+	c.Assert(foo.Complex128, Equals, -0.5-1.5i)
+	c.Assert(foo.ComplexList, DeepEquals, []complex128{1, 2i, 3 + 4i})
+	c.Assert(foo.ComplexMap, DeepEquals, map[string]complex128{"re": 1.5, "im": -2i})
+	c.Assert(foo.UintptrList, DeepEquals, []uintptr{1, 2})
+
 	c.Assert(foo.StringNoTag, Equals, "")
 	c.Assert(foo.String, Equals, "string")
 	c.Assert(foo.StringVal, Equals, StringVal("string"))
@@ -394,3 +418,576 @@ func (s *FillerSuite) TestIndirectType(c *C) {
 	c.Assert(fn(foo.Field(6)), Equals, reflect.TypeOf([]int{}))
 	c.Assert(fn(foo.Field(7)), Equals, reflect.TypeOf(map[int]int{}))
 }
+
+func (s *FillerSuite) TestSetDefaultsE(c *C) {
+	var foo ExampleBasic
+
+	err := SetDefaultsE(&foo)
+
+	errs, ok := err.(Errors)
+	c.Assert(ok, Equals, true)
+
+	var paths []string
+	for _, e := range errs {
+		fieldErr, ok := e.(*FieldError)
+		c.Assert(ok, Equals, true)
+		paths = append(paths, fieldErr.Path)
+	}
+	c.Assert(paths, DeepEquals, []string{
+		"BoolInvalid", "IntInvalid", "UintInvalid", "Float32Invalid", "Complex64Invalid", "DurationInvalid", "TimeInvalid", "Time",
+	})
+
+	fieldErr := errs[1].(*FieldError)
+	c.Assert(fieldErr.Type, Equals, reflect.TypeOf(0))
+	c.Assert(fieldErr.Tag, Equals, "invalid")
+	c.Assert(fieldErr.Error(), Equals, `IntInvalid (int): invalid default "invalid": strconv.ParseInt: parsing "invalid": invalid syntax`)
+
+	// fields are still filled when other fields fail
+	c.Assert(foo.Int, Equals, 1)
+	c.Assert(foo.IntList, DeepEquals, []int{1, 2, 3, 4})
+}
+
+type ExampleInvalidElement struct {
+	Struct   Struct                `default:"dive"`
+	IntPtr   *int                  `default:"1O"`
+	IntList  []int                 `default:"[1,x,3]"`
+	IntMap   map[int][]int         `default:"{1:[1],y:[2],3:[z]}"`
+	Children []ExampleInvalidChild `default:"dive"`
+}
+
+type ExampleInvalidChild struct {
+	Float float64 `default:"0.O1"`
+}
+
+func (s *FillerSuite) TestSetDefaultsEPath(c *C) {
+	foo := ExampleInvalidElement{Children: []ExampleInvalidChild{{}, {Float: 1}}}
+
+	err := SetDefaultsE(&foo)
+
+	var msgs []string
+	for _, e := range err.(Errors) {
+		msgs = append(msgs, e.(*FieldError).Path+" "+e.(*FieldError).Tag)
+	}
+	c.Assert(msgs, DeepEquals, []string{"IntPtr 1O", "IntList[1] x", "IntMap[y] y", "IntMap[3][0] z", "Children[0].Float 0.O1"})
+	c.Assert(foo.IntPtr, IsNil)
+	c.Assert(foo.IntList, DeepEquals, []int{1, 0, 3})
+}
+
+func (s *FillerSuite) TestSetDefaultsEInvalidVariable(c *C) {
+	var foo ExampleBasic
+	var nilPtr *ExampleBasic
+
+	c.Assert(SetDefaultsE(foo), Equals, ErrInvalidVariable)
+	c.Assert(SetDefaultsE(nilPtr), Equals, ErrInvalidVariable)
+	c.Assert(SetDefaultsE(new(int)), Equals, ErrInvalidVariable)
+	c.Assert(SetDefaultsE(&ExampleFuncsByKind{}), IsNil)
+}
+
+type ExampleArray struct {
+	IntArrayNoTag    [4]int
+	IntArrayInvalid  [4]int               `default:"invalid"`
+	IntArrayEmpty    [4]int               `default:"[]"`
+	IntArray         [4]int               `default:"[1,2,3,4]"`
+	IntArrayTooFew   [4]int               `default:"[1,2]"`
+	IntArrayTooMany  [2]int               `default:"[1,2,3,4]"`
+	Int2DArray       [2][2]int            `default:"[[1,2],[3,4]]"`
+	ArrayList        [][2]string          `default:"[[a,b],[c,d]]"`
+	ArrayMap         map[string][2]string `default:"{x:[a,b]}"`
+	Bytes            [5]byte              `default:"bytes"`
+	BytesLiteral     [2]byte              `default:"[1,2]"`
+	StructArray      [2]Struct
+	StructArrayValue [2]Struct `default:"dive"`
+	StructArrayOmit  [2]Struct `default:"omit"`
+}
+
+func (s *FillerSuite) TestArray(c *C) {
+	foo := ExampleArray{StructArrayValue: [2]Struct{{Integer: 7}}}
+
+	err := SetDefaultsE(&foo)
+
+	c.Assert(err, IsNil)
+	c.Assert(foo.IntArrayNoTag, Equals, [4]int{})
+	c.Assert(foo.IntArrayInvalid, Equals, [4]int{})
+	c.Assert(foo.IntArrayEmpty, Equals, [4]int{})
+	c.Assert(foo.IntArray, Equals, [4]int{1, 2, 3, 4})
+	c.Assert(foo.IntArrayTooFew, Equals, [4]int{1, 2, 0, 0})
+	c.Assert(foo.IntArrayTooMany, Equals, [2]int{1, 2})
+	c.Assert(foo.Int2DArray, Equals, [2][2]int{{1, 2}, {3, 4}})
+	c.Assert(foo.ArrayList, DeepEquals, [][2]string{{"a", "b"}, {"c", "d"}})
+	c.Assert(foo.ArrayMap, DeepEquals, map[string][2]string{"x": {"a", "b"}})
+	c.Assert(string(foo.Bytes[:]), Equals, "bytes")
+	c.Assert(foo.BytesLiteral, Equals, [2]byte{1, 2})
+	c.Assert(foo.StructArray, Equals, [2]Struct{{String: "string", Integer: 1}, {String: "string", Integer: 1}})
+	c.Assert(foo.StructArrayValue, Equals, [2]Struct{{String: "string", Integer: 7}, {String: "string", Integer: 1}})
+	c.Assert(foo.StructArrayOmit, Equals, [2]Struct{})
+
+	var bar ExampleArray
+
+	err = NewFiller(UseDefault(), UseStrict()).SetDefaultsE(&bar)
+
+	var paths []string
+	for _, e := range err.(Errors) {
+		paths = append(paths, e.(*FieldError).Path)
+	}
+	c.Assert(paths, DeepEquals, []string{"IntArrayInvalid", "IntArrayEmpty", "IntArrayTooFew", "IntArrayTooMany"})
+	c.Assert(bar.IntArrayTooFew, Equals, [4]int{1, 2, 0, 0})
+}
+
+var applied []string
+
+type Server struct {
+	Timeout     time.Duration `default:"10s"`
+	ReadTimeout time.Duration
+	Backends    []Backend `default:"dive"`
+	Primary     *Backend
+	Replicas    map[string]Backend `default:"dive"`
+}
+
+func (s *Server) ApplyDefaults() error {
+	applied = append(applied, "server")
+	if s.ReadTimeout == 0 {
+		s.ReadTimeout = s.Timeout / 2
+	}
+	return nil
+}
+
+type Backend struct {
+	Name string `default:"backend"`
+	Port int
+}
+
+func (b *Backend) ApplyDefaults() error {
+	applied = append(applied, "backend "+b.Name)
+	if b.Port == 0 {
+		return errors.New("port is required")
+	}
+	return nil
+}
+
+type ExampleDefaulter struct {
+	Server Server `default:"dive"`
+}
+
+func (e *ExampleDefaulter) ApplyDefaults() error {
+	applied = append(applied, "root")
+	return nil
+}
+
+func (s *FillerSuite) TestDefaulter(c *C) {
+	applied = nil
+	foo := ExampleDefaulter{Server: Server{
+		Backends: []Backend{{}, {Name: "b1", Port: 1}},
+		Replicas: map[string]Backend{"r1": {Port: 1}},
+	}}
+
+	err := SetDefaultsE(&foo)
+
+	c.Assert(err, ErrorMatches, `defaults: Server.Backends\[0\] \(defaults.Backend\): port is required; Server.Primary \(defaults.Backend\): port is required`)
+	// children are always completed before their parents
+	c.Assert(applied, DeepEquals, []string{"backend backend", "backend b1", "backend backend", "backend backend", "server", "root"})
+	c.Assert(foo.Server.Timeout, Equals, 10*time.Second)
+	c.Assert(foo.Server.ReadTimeout, Equals, 5*time.Second)
+	c.Assert(foo.Server.Backends[0].Name, Equals, "backend")
+	c.Assert(*foo.Server.Primary, Equals, Backend{Name: "backend"})
+	c.Assert(foo.Server.Replicas["r1"], Equals, Backend{Name: "backend", Port: 1})
+}
+
+type ExampleOverflow struct {
+	Int8      int8              `default:"300"`
+	Int8Min   int8              `default:"-129"`
+	Int8Max   int8              `default:"127"`
+	Uint8     uint8             `default:"256"`
+	Uint16    uint16            `default:"65535"`
+	Int32     int32             `default:"2147483648"`
+	Float32   float32           `default:"1e40"`
+	Precision float32           `default:"0.1"`
+	Complex64 complex64         `default:"1e40+1i"`
+	Int8List  []int8            `default:"[1,300]"`
+	Uint8Map  map[string]uint8  `default:"{a:1,b:256}"`
+	Int8Array [2]int8           `default:"[-1,-300]"`
+	KeyMap    map[int8]string   `default:"{128:a}"`
+	Nested    map[string][]int8 `default:"{a:[1,300]}"`
+}
+
+func (s *FillerSuite) TestOverflow(c *C) {
+	var foo ExampleOverflow
+
+	err := SetDefaultsE(&foo)
+
+	var msgs []string
+	for _, e := range err.(Errors) {
+		c.Assert(errors.Is(e, strconv.ErrRange), Equals, true)
+		msgs = append(msgs, e.(*FieldError).Path)
+	}
+	c.Assert(msgs, DeepEquals, []string{
+		"Int8", "Int8Min", "Uint8", "Int32", "Float32", "Complex64",
+		"Int8List[1]", `Uint8Map["b"]`, "Int8Array[1]", "KeyMap[128]", `Nested["a"][1]`,
+	})
+	c.Assert(err.(Errors)[0].Error(), Equals, `Int8 (int8): invalid default "300": strconv.ParseInt: parsing "300": value out of range`)
+
+	// every error is matched through Errors itself
+	var numErr *strconv.NumError
+	var fieldErr *FieldError
+	c.Assert(errors.Is(err, strconv.ErrRange), Equals, true)
+	c.Assert(errors.Is(err, ErrMalformedLiteral), Equals, false)
+	c.Assert(errors.As(err, &fieldErr), Equals, true)
+	c.Assert(fieldErr.Path, Equals, "Int8")
+	c.Assert(errors.As(err, &numErr), Equals, true)
+
+	// out of range values are never wrapped
+	c.Assert(foo.Int8, Equals, int8(0))
+	c.Assert(foo.Int8Min, Equals, int8(0))
+	c.Assert(foo.Int8Max, Equals, int8(127))
+	c.Assert(foo.Uint8, Equals, uint8(0))
+	c.Assert(foo.Uint16, Equals, uint16(65535))
+	c.Assert(foo.Int32, Equals, int32(0))
+	c.Assert(foo.Float32, Equals, float32(0))
+	c.Assert(foo.Precision, Equals, float32(0.1))
+	c.Assert(foo.Complex64, Equals, complex64(0))
+	c.Assert(foo.Int8List, DeepEquals, []int8{1, 0})
+	c.Assert(foo.Uint8Map, DeepEquals, map[string]uint8{"a": 1, "b": 0})
+	c.Assert(foo.Int8Array, Equals, [2]int8{-1, 0})
+	c.Assert(foo.Nested, DeepEquals, map[string][]int8{"a": {1, 0}})
+
+	// strict mode rejects the whole literal
+	var bar ExampleOverflow
+
+	c.Assert(NewFiller(UseDefault(), UseStrict()).SetDefaultsE(&bar), NotNil)
+	c.Assert(bar.Int8Max, Equals, int8(127))
+	c.Assert(bar.Int8List, IsNil)
+	c.Assert(bar.Uint8Map, IsNil)
+	c.Assert(bar.Int8Array, Equals, [2]int8{})
+	c.Assert(bar.KeyMap, IsNil)
+	c.Assert(bar.Nested, IsNil)
+}
+
+type ExampleIntegerLiteral struct {
+	Hex        int           `default:"0xFF"`
+	Octal      int           `default:"0o17"`
+	Binary     uint8         `default:"0b1010"`
+	Underscore int64         `default:"1_000_000"`
+	Negative   int32         `default:"-0x10"`
+	Leading    int           `default:"010"`
+	Invalid    int           `default:"1__0"`
+	Mode       os.FileMode   `default:"0644"`
+	ModeShort  os.FileMode   `default:"755"`
+	ModeHex    os.FileMode   `default:"0x1ff"`
+	ModeList   []os.FileMode `default:"[0600, 0o700]"`
+	ModeDigit  os.FileMode   `default:"0648"`
+}
+
+func (s *FillerSuite) TestIntegerLiteral(c *C) {
+	var foo ExampleIntegerLiteral
+
+	err := SetDefaultsE(&foo)
+
+	c.Assert(err, ErrorMatches, `defaults: Invalid \(int\): .*invalid syntax; ModeDigit \(fs.FileMode\): .*invalid syntax`)
+	c.Assert(foo.Hex, Equals, 255)
+	c.Assert(foo.Octal, Equals, 15)
+	c.Assert(foo.Binary, Equals, uint8(10))
+	c.Assert(foo.Underscore, Equals, int64(1000000))
+	c.Assert(foo.Negative, Equals, int32(-16))
+	// leading zero means octal as in Go
+	c.Assert(foo.Leading, Equals, 8)
+	c.Assert(foo.Invalid, Equals, 0)
+	// file modes are always octal unless prefixed otherwise
+	c.Assert(foo.Mode, Equals, os.FileMode(0644))
+	c.Assert(foo.ModeShort, Equals, os.FileMode(0755))
+	c.Assert(foo.ModeHex, Equals, os.FileMode(0777))
+	c.Assert(foo.ModeList, DeepEquals, []os.FileMode{0600, 0700})
+}
+
+type ExampleGenerated struct {
+	Name     string `default:"reflect"`
+	Nested   ExampleGeneratedNested
+	Computed string
+}
+
+type ExampleGeneratedNested struct {
+	Name string `default:"reflect"`
+}
+
+func (e *ExampleGenerated) SetGeneratedDefaults() {
+	if e.Name == "" {
+		e.Name = "generated"
+	}
+}
+
+func (e *ExampleGenerated) ApplyDefaults() error {
+	e.Computed = e.Name + "!"
+	return nil
+}
+
+type ExampleHandWritten struct {
+	Name   string `default:"reflect"`
+	Nested ExampleGeneratedNested
+}
+
+// SetDefaults is the usual wrapper which must not be taken for a generated method
+func (e *ExampleHandWritten) SetDefaults() {
+	SetDefaults(e)
+}
+
+func (s *FillerSuite) TestGenerated(c *C) {
+	var foo ExampleGenerated
+	opts := packageOptions()
+
+	// the generated method replaces filling field by field, including nested structs, then ApplyDefaults is called as usual
+	generated := ExampleGenerated{Name: "generated", Computed: "generated!"}
+	c.Assert(NewFiller(opts...).SetDefaultsE(&foo), IsNil)
+	c.Assert(foo, Equals, generated)
+
+	reflective := ExampleGenerated{Name: "reflect", Nested: ExampleGeneratedNested{Name: "reflect"}, Computed: "reflect!"}
+	for _, t := range []struct {
+		filler   Filler
+		expected ExampleGenerated
+	}{
+		{NewFiller(opts...), generated},
+		{NewFiller(append(opts, IgnoreGenerated())...), reflective},
+		{NewFiller(append(opts, UseStrict())...), reflective},
+		{NewFiller(append(opts, UseDefaultTag("value"))...), ExampleGenerated{Computed: "!"}},
+		{NewFiller(append(opts, UseEnvTag("env"))...), reflective},
+		{NewFiller(UseDefault()), reflective},
+		{NewFiller(append(opts, UseTimeFormat(time.RFC1123))...), reflective},
+		{NewFiller(append(opts, UseDefaultType(time.Second))...), reflective},
+		{NewFiller(append(opts, UseKindFunc(reflect.Bool, func(*Field) {}))...), reflective},
+		{NewFiller(append(opts, UseTypeFunc(reflect.TypeOf(""), func(*Field) {}))...), reflective},
+		{NewFiller(append(opts, UseTypeParser(url.Parse))...), reflective},
+		{NewFiller(append(opts, UseByteSizes())...), reflective},
+		{NewFiller(append(opts, UseMaxDepth(1))...), reflective},
+		{NewFiller(append(opts, UseDefault())...), reflective},
+	} {
+		var bar ExampleGenerated
+		c.Assert(t.filler.SetDefaultsE(&bar), IsNil)
+		c.Assert(bar, Equals, t.expected)
+	}
+
+	// fields are reported one by one
+	var baz ExampleGenerated
+	report, err := NewFiller(opts...).SetDefaultsWithReport(&baz)
+
+	c.Assert(err, IsNil)
+	c.Assert(report, HasLen, 2)
+	c.Assert(baz, Equals, reflective)
+
+	// a hand-written SetDefaults is neither called back nor does it disable tags
+	var qux ExampleHandWritten
+	qux.SetDefaults()
+	c.Assert(qux, Equals, ExampleHandWritten{Name: "reflect", Nested: ExampleGeneratedNested{Name: "reflect"}})
+}
+
+func (s *FillerSuite) TestCacheConcurrency(c *C) {
+	filler := NewFiller(UseDefault(), UseTimeFormat(time.RFC3339), ParseDuration())
+
+	var wg sync.WaitGroup
+	results := make([]ExampleBasic, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			filler.SetDefaults(&results[i])
+		}(i)
+	}
+	wg.Wait()
+
+	var expected ExampleBasic
+	filler.SetDefaults(&expected)
+	for _, result := range results {
+		c.Assert(result, DeepEquals, expected)
+	}
+
+	// literal slices are shared by the cache only before parsing
+	results[0].IntList[0] = 7
+	c.Assert(results[1].IntList, DeepEquals, []int{1, 2, 3, 4})
+}
+
+type ExampleCacheLiteral struct {
+	Tags   []string         `default:"[a,b]"`
+	Nested map[string][]int `default:"{a:[${I}]}"`
+}
+
+func (s *FillerSuite) TestCacheLiterals(c *C) {
+	literals := func(f *filler) []string {
+		var exprs []string
+		f.cache.literals.Range(func(key, _ interface{}) bool {
+			exprs = append(exprs, key.(string))
+			return true
+		})
+		sort.Strings(exprs)
+		return exprs
+	}
+
+	f := newFiller(UseDefault())
+	var foo ExampleCacheLiteral
+	f.SetDefaults(&foo)
+	c.Assert(literals(f), DeepEquals, []string{"${I}", "a,b", "a:[${I}]"})
+
+	// literals looked up at run time are never cached
+	var i int
+	f = newFiller(UseDefault(), UseInterpolation(func(string) (string, bool) {
+		return strconv.Itoa(i), true
+	}), UseValues(map[string]string{"tags": "[x,y]"}))
+	for i = 0; i < 3; i++ {
+		var bar ExampleCacheLiteral
+		f.SetDefaults(&bar)
+		c.Assert(bar.Tags, DeepEquals, []string{"x", "y"})
+		c.Assert(bar.Nested, DeepEquals, map[string][]int{"a": {i}})
+	}
+	c.Assert(literals(f), HasLen, 0)
+}
+
+type ExampleParsedLiteral struct {
+	Nested  map[string][]*int `default:"{a:[1,2]}"`
+	Array   [2]int            `default:"[1]"`
+	Invalid []int             `default:"[1,x]"`
+}
+
+func (s *FillerSuite) TestCacheParsedLiterals(c *C) {
+	f := newFiller(UseDefault())
+	var foo, bar ExampleParsedLiteral
+	c.Assert(f.SetDefaultsE(&foo), ErrorMatches, `defaults: Invalid\[1\] .*`)
+	*foo.Nested["a"][0] = 7
+	foo.Array[0] = 7
+
+	// parsed values are copied, and literals failing to parse report their errors every time
+	c.Assert(f.SetDefaultsE(&bar), ErrorMatches, `defaults: Invalid\[1\] .*`)
+	c.Assert(*bar.Nested["a"][0], Equals, 1)
+	c.Assert(bar.Array, Equals, [2]int{1, 0})
+
+	var count int
+	f.parsed.Range(func(_, _ interface{}) bool {
+		count++
+		return true
+	})
+	c.Assert(count, Equals, 2)
+
+	// a lenient parse is never taken for a strict one
+	var baz ExampleParsedLiteral
+	f.Strict = true
+	c.Assert(f.SetDefaultsE(&baz), ErrorMatches, `defaults: Array .*: malformed literal: got 1 elements .*`)
+}
+
+func BenchmarkSetDefaultsCold(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		var foo ExampleBasic
+		NewFiller(UseDefault(), UseTimeFormat(time.RFC3339), ParseDuration()).SetDefaults(&foo)
+	}
+}
+
+func BenchmarkSetDefaultsWarm(b *testing.B) {
+	filler := NewFiller(UseDefault(), UseTimeFormat(time.RFC3339), ParseDuration())
+	for i := 0; i < b.N; i++ {
+		var foo ExampleBasic
+		filler.SetDefaults(&foo)
+	}
+}
+
+type ExampleQuoted struct {
+	StringList    []string              `default:"[\"a,b\", 'c]d', C:\\dir, \" g \"]"`
+	SpacedList    []int                 `default:"[ 1, 2 ,3 ]"`
+	EscapedList   []string              `default:"['it\\'s', \"say \\\"hi\\\"\", \"tab\\tnew\\nline\"]"`
+	URLMap        map[string]int        `default:"{\"http://a:80\": 1, 'https://b:443' : 2}"`
+	NestedQuoted  []map[string][]string `default:"[{\"a:b\": [\"x,y\", '[z]']}, {c: [\"}\"]}]"`
+	SpacedMap     map[int][]string      `default:"{ 1 : [ a , b ], 2:[c]}"`
+	Apostrophe    []string              `default:"[don't,stop, 'a,b']"`
+	ApostropheMap map[string]string     `default:"{don't:stop, rock'n'roll:'a,b'}"`
+	Unterminated  []string              `default:"[\"a,b]"`
+}
+
+func (s *FillerSuite) TestQuotedLiteral(c *C) {
+	var foo ExampleQuoted
+
+	err := NewFiller(UseDefault(), UseStrict()).SetDefaultsE(&foo)
+
+	c.Assert(err, ErrorMatches, `defaults: Unterminated \(\[\]string\): invalid default .*: malformed literal: missing closing quote '"'`)
+	c.Assert(foo.StringList, DeepEquals, []string{"a,b", "c]d", `C:\dir`, " g "})
+	c.Assert(foo.SpacedList, DeepEquals, []int{1, 2, 3})
+	c.Assert(foo.EscapedList, DeepEquals, []string{"it's", `say "hi"`, "tab\tnew\nline"})
+	c.Assert(foo.URLMap, DeepEquals, map[string]int{"http://a:80": 1, "https://b:443": 2})
+	c.Assert(foo.NestedQuoted, DeepEquals, []map[string][]string{{"a:b": {"x,y", "[z]"}}, {"c": {"}"}}})
+	c.Assert(foo.SpacedMap, DeepEquals, map[int][]string{1: {"a", "b"}, 2: {"c"}})
+	// quotes only open at the start of a token, apostrophes inside bare tokens are kept as is
+	c.Assert(foo.Apostrophe, DeepEquals, []string{"don't", "stop", "a,b"})
+	c.Assert(foo.ApostropheMap, DeepEquals, map[string]string{"don't": "stop", "rock'n'roll": "a,b"})
+	c.Assert(foo.Unterminated, IsNil)
+
+	var bar ExampleQuoted
+	c.Assert(NewFiller(UseDefault()).SetDefaultsE(&bar), IsNil)
+	c.Assert(bar.Apostrophe, DeepEquals, foo.Apostrophe)
+}
+
+type ExampleFieldPath struct {
+	Name    string                        `default:"name" usage:"name of the server"`
+	NamePtr *string                       `default:"name"`
+	Servers []ExampleFieldServer          `default:"dive"`
+	Limits  map[string]ExampleFieldServer `default:"dive"`
+	Literal map[string][]string           `default:"{api:[a,b]}"`
+}
+
+type ExampleFieldServer struct {
+	TLS struct {
+		CertFile string `default:"cert.pem"`
+	}
+}
+
+func (s *FillerSuite) TestFieldPath(c *C) {
+	type visit struct {
+		path  string
+		name  string
+		usage string
+		index int
+		key   interface{}
+	}
+	var visits []visit
+
+	f := newFiller(UseDefault())
+	fillString := f.FuncsByKind[reflect.String]
+	fillStruct := f.FuncsByKind[reflect.Struct]
+	f.FuncsByKind[reflect.Struct] = func(field *Field) {
+		if field.Parent != nil && field.Parent.Value.Kind() == reflect.Slice {
+			visits = append(visits, visit{path: field.Path(), index: field.Index})
+		}
+		if field.Parent != nil && field.Parent.Value.Kind() == reflect.Map {
+			visits = append(visits, visit{path: field.Path(), key: field.Key.Interface()})
+		}
+		fillStruct(field)
+	}
+	f.FuncsByKind[reflect.String] = func(field *Field) {
+		var key interface{}
+		if field.Key.IsValid() {
+			key = field.Key.Interface()
+		}
+		visits = append(visits, visit{
+			path:  field.Path(),
+			name:  field.StructField.Name,
+			usage: field.StructField.Tag.Get("usage"),
+			index: field.Index,
+			key:   key,
+		})
+		fillString(field)
+	}
+
+	foo := ExampleFieldPath{
+		Servers: []ExampleFieldServer{{}, {}, {}},
+		Limits:  map[string]ExampleFieldServer{"api": {}},
+	}
+	f.SetDefaults(&foo)
+
+	c.Assert(visits, DeepEquals, []visit{
+		{path: "Name", name: "Name", usage: "name of the server"},
+		{path: "NamePtr", name: "NamePtr"},
+		{path: "Servers[0]", index: 0},
+		{path: "Servers[0].TLS.CertFile", name: "CertFile"},
+		{path: "Servers[1]", index: 1},
+		{path: "Servers[1].TLS.CertFile", name: "CertFile"},
+		{path: "Servers[2]", index: 2},
+		{path: "Servers[2].TLS.CertFile", name: "CertFile"},
+		{path: `Limits["api"]`, key: "api"},
+		{path: `Limits["api"].TLS.CertFile`, name: "CertFile"},
+		{path: `Literal["api"]`},
+		{path: `Literal["api"][0]`, index: 0},
+		{path: `Literal["api"][1]`, index: 1},
+	})
+	c.Assert(foo.Servers[2].TLS.CertFile, Equals, "cert.pem")
+	c.Assert(foo.Limits["api"].TLS.CertFile, Equals, "cert.pem")
+}
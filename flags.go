@@ -0,0 +1,243 @@
+package defaults
+
+import (
+	"encoding"
+	"flag"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	flagTag  = "flag"
+	usageTag = "usage"
+)
+
+// BindFlags fills variable with its defaults then defines a flag on fs for every field the filler can parse,
+// named after the lowercase path of the field, e.g. server.port, and described by its usage tag.
+// Use the flag tag to rename a field, or a nested struct, and "-" to skip it.
+// Flag defaults are the filled values and flag values are parsed exactly like default tags, e.g. -tags=[a,b],
+// except malformed literals are always rejected as in strict mode.
+// The package level filler is used unless any option is given.
+func BindFlags(fs *flag.FlagSet, variable interface{}, opts ...Option) error {
+	f := GetDefaultFiller().(*filler)
+	if len(opts) > 0 {
+		f = newFiller(opts...)
+	}
+
+	if err := f.SetDefaultsE(variable); err != nil {
+		return err
+	}
+
+	value := reflect.ValueOf(variable).Elem()
+	f.bindStruct(fs, func(bool) reflect.Value { return value }, value.Type(), "", "", map[reflect.Type]bool{})
+	return nil
+}
+
+// accessor returns the value bound to a flag, nil pointers on the way are allocated only when alloc is set,
+// otherwise an invalid value is returned
+type accessor func(alloc bool) reflect.Value
+
+func (f *filler) bindStruct(fs *flag.FlagSet, get accessor, typ reflect.Type, name, path string, visiting map[reflect.Type]bool) {
+	visiting[typ] = true
+	defer delete(visiting, typ)
+
+	for _, fieldPlan := range f.structPlan(typ).fields {
+		fieldPlan := fieldPlan
+		segment := strings.ToLower(fieldPlan.field.Name)
+		if tag, ok := fieldPlan.field.Tag.Lookup(flagTag); ok {
+			if tag == "-" {
+				continue
+			}
+			segment = tag
+		}
+
+		fieldGet := func(alloc bool) reflect.Value {
+			if parent := get(alloc); parent.IsValid() {
+				return parent.Field(fieldPlan.index)
+			}
+			return reflect.Value{}
+		}
+		f.bindField(fs, fieldGet, fieldPlan.field, joinPath(name, segment), joinPath(path, fieldPlan.field.Name), visiting)
+	}
+}
+
+func (f *filler) bindField(fs *flag.FlagSet, get accessor, field reflect.StructField, name, path string, visiting map[reflect.Type]bool) {
+	typ := field.Type
+	switch {
+	case f.isFlag(typ):
+		fs.Var(&flagValue{filler: f, get: get, field: field, path: path}, name, field.Tag.Get(usageTag))
+	case typ.Kind() == reflect.Struct:
+		f.bindStruct(fs, get, typ, name, path, visiting)
+	case typ.Kind() == reflect.Ptr && typ.Elem().Kind() == reflect.Struct:
+		// Stop at self-referential types, their flags would never end
+		if visiting[typ.Elem()] {
+			return
+		}
+		elemGet := func(alloc bool) reflect.Value {
+			ptr := get(alloc)
+			if !ptr.IsValid() || ptr.IsNil() && !alloc {
+				return reflect.Value{}
+			}
+			if ptr.IsNil() {
+				ptr.Set(reflect.New(typ.Elem()))
+			}
+			return ptr.Elem()
+		}
+		f.bindStruct(fs, elemGet, typ.Elem(), name, path, visiting)
+	}
+}
+
+// isFlag tells whether a value of the type is parsed from a single tag, rather than filled field by field or not at all
+func (f *filler) isFlag(typ reflect.Type) bool {
+	if _, ok := f.ParsersByType[typ]; ok {
+		return true
+	}
+	// Funcs of the type, e.g. of UseDefaultType, may ignore the tag, only the one of the time layout parses it.
+	// Any other type is bound like any type of its kind, which parses the tag before the func of the type runs.
+	_, registered := f.FuncsByType[typ]
+	if registered && typ == timeType {
+		return f.timeLayout != ""
+	}
+	if !registered && f.TextUnmarshaler && reflect.PtrTo(typ).Implements(textUnmarshalerType) {
+		return true
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct, reflect.Interface:
+		return false
+	case reflect.Ptr:
+		return f.isFlag(typ.Elem())
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return !f.isStructContainer(&Field{Value: reflect.New(typ).Elem()})
+	default:
+		_, ok := f.FuncsByKind[typ.Kind()]
+		return ok
+	}
+}
+
+func joinPath(path, segment string) string {
+	if path == "" {
+		return segment
+	}
+	return path + "." + segment
+}
+
+// flagValue is a flag.Getter setting the field bound to the flag by the fill funcs of the filler
+type flagValue struct {
+	filler *filler
+	get    accessor
+	field  reflect.StructField
+	path   string
+}
+
+func (v *flagValue) String() string {
+	// flag package calls String of a zero flagValue to find out the zero default
+	if v.get == nil {
+		return ""
+	}
+	// zero values are left empty so that flag package does not print them as defaults
+	if value := v.get(false); value.IsValid() && !value.IsZero() {
+		return formatValue(value, false)
+	}
+	return ""
+}
+
+// Set parses s into the field, malformed literals are always rejected rather than silently left empty
+func (v *flagValue) Set(s string) error {
+	value := reflect.New(v.field.Type).Elem()
+	state := &fillState{strict: true}
+	v.filler.fillField(&Field{
+		Value:       value,
+		Tag:         s,
+		StructField: v.field,
+		segment:     v.path,
+		state:       state,
+	})
+	if len(state.errs) > 0 {
+		return state.errs[0].(*FieldError).Err
+	}
+
+	v.get(true).Set(value)
+	return nil
+}
+
+func (v *flagValue) Get() interface{} {
+	if value := v.get(false); value.IsValid() {
+		return value.Interface()
+	}
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool {
+	typ := v.field.Type
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.Bool
+}
+
+// formatValue returns the value in the syntax of default tags, e.g. [a,b] or {a:1}, values of literals are quoted as needed
+func formatValue(value reflect.Value, inLiteral bool) string {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return ""
+		}
+		value = value.Elem()
+	}
+
+	switch {
+	case value.Type() == fileModeType:
+		return fmt.Sprintf("%#o", value.Uint())
+	case value.Type() == reflect.TypeOf(time.Duration(0)):
+		return quoteToken(value.Interface().(time.Duration).String(), inLiteral)
+	case value.CanInterface():
+		if marshaler, ok := value.Interface().(encoding.TextMarshaler); ok {
+			if text, err := marshaler.MarshalText(); err == nil {
+				return quoteToken(string(text), inLiteral)
+			}
+		}
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return quoteToken(value.String(), inLiteral)
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return ""
+		}
+		if value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8 {
+			return quoteToken(string(value.Bytes()), inLiteral)
+		}
+
+		elems := make([]string, value.Len())
+		for i := range elems {
+			elems[i] = formatValue(value.Index(i), true)
+		}
+		return "[" + strings.Join(elems, ",") + "]"
+	case reflect.Map:
+		if value.IsNil() {
+			return ""
+		}
+
+		entries := make([]string, 0, value.Len())
+		for _, key := range value.MapKeys() {
+			entries = append(entries, formatValue(key, true)+":"+formatValue(value.MapIndex(key), true))
+		}
+		sort.Strings(entries)
+		return "{" + strings.Join(entries, ",") + "}"
+	default:
+		return fmt.Sprint(value.Interface())
+	}
+}
+
+// quoteToken quotes a value of a literal when it holds any character tokenizeValues would split or trim
+func quoteToken(s string, inLiteral bool) string {
+	if !inLiteral || s != "" && s == strings.TrimSpace(s) && !strings.ContainsAny(s, `,:[]{}"'\`) {
+		return s
+	}
+
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,147 @@
+package defaults
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type FlagsSuite struct{}
+
+var _ = Suite(&FlagsSuite{})
+
+type ExampleFlags struct {
+	Port     int                `default:"8080" usage:"port to listen on"`
+	Debug    bool               `usage:"enable debug logs"`
+	Timeout  time.Duration      `default:"30s"`
+	Started  time.Time          `default:"2020-01-01T00:00:00Z"`
+	Mode     os.FileMode        `default:"0644"`
+	Tags     []string           `default:"[a, \"b,c\"]"`
+	Limits   map[string]int     `default:"{api:10}"`
+	Ratio    *float64           `default:"0.5"`
+	Address  string             `flag:"addr" default:"localhost"`
+	Secret   string             `flag:"-" default:"secret"`
+	Server   ExampleFlagsServer `flag:"srv"`
+	TLS      *ExampleFlagsTLS
+	Next     *ExampleFlags
+	Backends []ExampleFlagsServer
+	Handler  interface{}
+}
+
+type ExampleFlagsServer struct {
+	Host string `default:"example.com" usage:"server host"`
+	Port int    `default:"80"`
+}
+
+type ExampleFlagsTLS struct {
+	Cert string `usage:"certificate file"`
+}
+
+func (s *FlagsSuite) TestBindFlags(c *C) {
+	var foo ExampleFlags
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+
+	c.Assert(BindFlags(fs, &foo), IsNil)
+
+	var names []string
+	defValues := map[string]string{}
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, f.Name)
+		defValues[f.Name] = f.DefValue
+	})
+	c.Assert(names, DeepEquals, []string{
+		"addr", "debug", "limits", "mode", "port", "ratio", "srv.host", "srv.port", "started", "tags", "timeout", "tls.cert",
+	})
+	c.Assert(defValues, DeepEquals, map[string]string{
+		"addr":     "localhost",
+		"debug":    "",
+		"limits":   "{api:10}",
+		"mode":     "0644",
+		"port":     "8080",
+		"ratio":    "0.5",
+		"srv.host": "example.com",
+		"srv.port": "80",
+		"started":  "2020-01-01T00:00:00Z",
+		"tags":     `[a,"b,c"]`,
+		"timeout":  "30s",
+		"tls.cert": "",
+	})
+	c.Assert(fs.Lookup("port").Usage, Equals, "port to listen on")
+	// defaults are filled before parsing
+	c.Assert(foo.Secret, Equals, "secret")
+	c.Assert(foo.TLS, IsNil)
+
+	err := fs.Parse([]string{
+		"-port=9090", "-debug", "-timeout=1m", "-mode=0600", "-tags=[x, y]", "-limits={web:5}",
+		"-ratio=0.25", "-srv.host=api.example.com", "-tls.cert=cert.pem",
+	})
+
+	c.Assert(err, IsNil)
+	c.Assert(foo.Port, Equals, 9090)
+	c.Assert(foo.Debug, Equals, true)
+	c.Assert(foo.Timeout, Equals, time.Minute)
+	c.Assert(foo.Mode, Equals, os.FileMode(0600))
+	c.Assert(foo.Tags, DeepEquals, []string{"x", "y"})
+	c.Assert(foo.Limits, DeepEquals, map[string]int{"web": 5})
+	c.Assert(*foo.Ratio, Equals, 0.25)
+	c.Assert(foo.Server, Equals, ExampleFlagsServer{Host: "api.example.com", Port: 80})
+	c.Assert(foo.TLS, DeepEquals, &ExampleFlagsTLS{Cert: "cert.pem"})
+	c.Assert(fs.Lookup("port").Value.(flag.Getter).Get(), Equals, 9090)
+}
+
+func (s *FlagsSuite) TestBindFlagsErrors(c *C) {
+	var foo ExampleFlags
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(&bytes.Buffer{})
+
+	c.Assert(BindFlags(fs, &foo), IsNil)
+	c.Assert(fs.Parse([]string{"-port=abc"}), ErrorMatches,
+		`invalid value "abc" for flag -port: strconv.ParseInt: parsing "abc": invalid syntax`)
+	c.Assert(fs.Parse([]string{"-srv.port=99999999999999999999"}), ErrorMatches, `invalid value .* value out of range`)
+	c.Assert(foo.Port, Equals, 8080)
+	// malformed literals are rejected even without strict mode
+	c.Assert(fs.Parse([]string{"-tags=x,y"}), ErrorMatches,
+		`invalid value "x,y" for flag -tags: malformed literal: slice value must be enclosed in \[\]`)
+	c.Assert(fs.Parse([]string{"-limits={web:5,api}"}), ErrorMatches, `invalid value .* missing ':' in map entry "api"`)
+	c.Assert(foo.Tags, DeepEquals, []string{"a", "b,c"})
+	c.Assert(foo.Limits, DeepEquals, map[string]int{"api": 10})
+
+	c.Assert(BindFlags(flag.NewFlagSet("test", flag.ContinueOnError), foo), Equals, ErrInvalidVariable)
+	c.Assert(BindFlags(flag.NewFlagSet("test", flag.ContinueOnError), &ExampleGenericInvalid{}), ErrorMatches, "defaults: Int .*")
+
+	// options replace the package level filler
+	var bar ExampleFlagsServer
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+
+	c.Assert(BindFlags(fs, &bar, UseDefault(), UseDefaultTag("value")), IsNil)
+	c.Assert(bar, Equals, ExampleFlagsServer{})
+	c.Assert(fs.Lookup("host").DefValue, Equals, "")
+	c.Assert(fs.Lookup("port").Usage, Equals, "")
+}
+
+type ExampleFlagsRegistered struct {
+	Reg     ExampleFlagsTLS
+	Started time.Time
+	Name    Default
+}
+
+func (s *FlagsSuite) TestBindFlagsDefaultType(c *C) {
+	var foo ExampleFlagsRegistered
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+
+	// types filled regardless of their tag are never bound as a whole, structs are bound field by field instead
+	c.Assert(BindFlags(fs, &foo, UseDefault(), UseTimeFormat(time.RFC3339), UseDefaultType(ExampleFlagsTLS{Cert: "reg.pem"}),
+		UseDefaultType(time.Unix(0, 0).UTC()), UseDefaultType(Default("name"))), IsNil)
+
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) { names = append(names, f.Name) })
+	c.Assert(names, DeepEquals, []string{"name", "reg.cert"})
+	c.Assert(foo, Equals, ExampleFlagsRegistered{Reg: ExampleFlagsTLS{Cert: "reg.pem"}, Started: time.Unix(0, 0).UTC(), Name: "name"})
+
+	c.Assert(fs.Parse([]string{"-reg.cert=flag.pem", "-name=flag"}), IsNil)
+	c.Assert(foo.Reg, Equals, ExampleFlagsTLS{Cert: "flag.pem"})
+	c.Assert(foo.Name, Equals, Default("flag"))
+}
@@ -0,0 +1,30 @@
+package defaults
+
+// New returns a value of struct type T filled by the default filler together with every error like SetDefaultsE.
+// T can not be constrained to struct types, any other type, including a pointer to a struct, returns ErrInvalidVariable.
+func New[T any]() (T, error) {
+	var value T
+	err := SetDefaultsE(&value)
+	return value, err
+}
+
+// MustNew works like New but panics on error
+func MustNew[T any]() T {
+	value, err := New[T]()
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// Fill fills the struct value with the default filler and returns every error like SetDefaultsE
+func Fill[T any](value *T) error {
+	return SetDefaultsE(value)
+}
+
+// MustFill works like Fill but panics on error
+func MustFill[T any](value *T) {
+	if err := Fill(value); err != nil {
+		panic(err)
+	}
+}
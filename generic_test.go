@@ -0,0 +1,55 @@
+package defaults
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type GenericSuite struct{}
+
+var _ = Suite(&GenericSuite{})
+
+type ExampleGenericInvalid struct {
+	Int int    `default:"invalid"`
+	Str string `default:"string"`
+}
+
+func (s *GenericSuite) TestNew(c *C) {
+	foo, err := New[Struct]()
+	c.Assert(err, IsNil)
+	c.Assert(foo, Equals, Struct{String: "string", Integer: 1})
+
+	bar, err := New[ExampleGenericInvalid]()
+	c.Assert(err, ErrorMatches, `defaults: Int \(int\): .*`)
+	c.Assert(bar, Equals, ExampleGenericInvalid{Str: "string"})
+
+	// types other than structs are rejected rather than silently left zero
+	_, err = New[int]()
+	c.Assert(err, Equals, ErrInvalidVariable)
+	ptr, err := New[*Struct]()
+	c.Assert(err, Equals, ErrInvalidVariable)
+	c.Assert(ptr, IsNil)
+
+	c.Assert(MustNew[Struct](), Equals, Struct{String: "string", Integer: 1})
+	c.Assert(func() { MustNew[ExampleGenericInvalid]() }, PanicMatches, `defaults: Int \(int\): invalid default "invalid": .*`)
+	c.Assert(func() { MustNew[int]() }, PanicMatches, ErrInvalidVariable.Error())
+	c.Assert(func() { MustNew[*Struct]() }, PanicMatches, ErrInvalidVariable.Error())
+}
+
+func (s *GenericSuite) TestFill(c *C) {
+	foo := Struct{Integer: 7}
+
+	c.Assert(Fill(&foo), IsNil)
+	c.Assert(foo, Equals, Struct{String: "string", Integer: 7})
+
+	var bar ExampleGenericInvalid
+
+	c.Assert(Fill(&bar), ErrorMatches, `defaults: Int \(int\): .*`)
+	c.Assert(bar.Str, Equals, "string")
+	c.Assert(Fill(new(int)), Equals, ErrInvalidVariable)
+
+	var baz Struct
+
+	MustFill(&baz)
+	c.Assert(baz, Equals, Struct{String: "string", Integer: 1})
+	c.Assert(func() { MustFill(&ExampleGenericInvalid{}) }, PanicMatches, `defaults: Int .*`)
+}
@@ -0,0 +1,207 @@
+// Code generated by defaults-gen; DO NOT EDIT.
+
+package gentest
+
+import "time"
+
+// SetDefaults fills the zero fields of Config with their default tag, then calls ApplyDefaults ignoring its error
+func (s *Config) SetDefaults() {
+	s.SetGeneratedDefaults()
+	_ = s.ApplyDefaults()
+}
+
+// SetGeneratedDefaults fills the zero fields of Config with their default tag, without calling any defaults.Defaulter.
+// defaults.SetDefaults calls it instead of filling the fields by reflection.
+func (s *Config) SetGeneratedDefaults() {
+	if s.Name == "" {
+		s.Name = "app"
+	}
+	if !s.Debug {
+		s.Debug = true
+	}
+	if s.Workers == 0 {
+		s.Workers = 8
+	}
+	if s.Ratio == 0 {
+		s.Ratio = 0.75
+	}
+	if s.Port == 0 {
+		s.Port = 8080
+	}
+	if s.Role == "" {
+		s.Role = Role("admin")
+	}
+	if s.Interval == 0 {
+		s.Interval = time.Duration(90000000000)
+	}
+	if s.Timeout == 0 {
+		s.Timeout = Timeout(15)
+	}
+	if s.Started == (time.Time{}) {
+		s.Started = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	}
+	if s.Quoted == "" {
+		s.Quoted = "omit"
+	}
+	if s.Tags == nil {
+		s.Tags = []string{"a", "b,c", " d "}
+	}
+	if s.Matrix == nil {
+		s.Matrix = [][]int{[]int{1, 2}, []int{3}}
+	}
+	if s.IDs == nil {
+		s.IDs = IDs{1, 2, 3}
+	}
+	if s.NoIDs == nil {
+		s.NoIDs = []int{}
+	}
+	if s.Pair == ([2]int{}) {
+		s.Pair = [2]int{4, 5}
+	}
+	if s.Labels == nil {
+		s.Labels = map[string]int{"a": 1, "b": 2}
+	}
+	if s.Roles == nil {
+		s.Roles = map[string][]Role{"dev": []Role{Role("read"), Role("write")}}
+	}
+	if s.Raw == nil {
+		s.Raw = []byte("raw")
+	}
+	if s.NoRaw == nil {
+		s.NoRaw = []byte("")
+	}
+	if s.Retries == nil {
+		s.Retries = func() *int { v := 3; return &v }()
+	}
+	s.Server.SetGeneratedDefaults()
+	if isZeroServer(&s.Fallback) {
+		s.Fallback.SetGeneratedDefaults()
+	}
+	if s.Admin == nil {
+		v1 := new(Server)
+		v1.SetGeneratedDefaults()
+		if !isZeroServer(v1) {
+			s.Admin = v1
+		}
+	}
+	if s.Backup != nil {
+		s.Backup.SetGeneratedDefaults()
+	} else {
+		v2 := new(Server)
+		v2.SetGeneratedDefaults()
+		if !isZeroServer(v2) {
+			s.Backup = v2
+		}
+	}
+	for i3 := range s.Replicas {
+		s.Replicas[i3].SetGeneratedDefaults()
+	}
+	for i4 := range s.Pointers {
+		if s.Pointers[i4] != nil {
+			s.Pointers[i4].SetGeneratedDefaults()
+		} else {
+			v5 := new(Server)
+			v5.SetGeneratedDefaults()
+			if !isZeroServer(v5) {
+				s.Pointers[i4] = v5
+			}
+		}
+	}
+	for k6, v7 := range s.Limits {
+		if v7 != nil {
+			v7.SetGeneratedDefaults()
+		} else {
+			v8 := new(Limit)
+			v8.SetGeneratedDefaults()
+			if !isZeroLimit(v8) {
+				v7 = v8
+			}
+		}
+		s.Limits[k6] = v7
+	}
+	if isZeroLimit(&s.Fixed[0]) && isZeroLimit(&s.Fixed[1]) {
+		for i9 := range s.Fixed {
+			if isZeroLimit(&s.Fixed[i9]) {
+				s.Fixed[i9].SetGeneratedDefaults()
+			}
+		}
+	}
+	if isZeroLimit(&s.Limit) {
+		s.Limit.SetGeneratedDefaults()
+	}
+}
+
+// SetDefaults fills the zero fields of Server with their default tag
+func (s *Server) SetDefaults() {
+	s.SetGeneratedDefaults()
+}
+
+// SetGeneratedDefaults fills the zero fields of Server with their default tag, without calling any defaults.Defaulter.
+// defaults.SetDefaults calls it instead of filling the fields by reflection.
+func (s *Server) SetGeneratedDefaults() {
+	if s.Host == "" {
+		s.Host = "localhost"
+	}
+	if s.Port == 0 {
+		s.Port = 80
+	}
+	if s.TLS == nil {
+		v10 := new(TLS)
+		v10.SetGeneratedDefaults()
+		if !isZeroTLS(v10) {
+			s.TLS = v10
+		}
+	}
+}
+
+// SetDefaults fills the zero fields of TLS with their default tag
+func (s *TLS) SetDefaults() {
+	s.SetGeneratedDefaults()
+}
+
+// SetGeneratedDefaults fills the zero fields of TLS with their default tag, without calling any defaults.Defaulter.
+// defaults.SetDefaults calls it instead of filling the fields by reflection.
+func (s *TLS) SetGeneratedDefaults() {
+	if !s.Enabled {
+		s.Enabled = true
+	}
+	if s.Cert == "" {
+		s.Cert = "cert.pem"
+	}
+}
+
+// SetDefaults fills the zero fields of Limit with their default tag
+func (s *Limit) SetDefaults() {
+	s.SetGeneratedDefaults()
+}
+
+// SetGeneratedDefaults fills the zero fields of Limit with their default tag, without calling any defaults.Defaulter.
+// defaults.SetDefaults calls it instead of filling the fields by reflection.
+func (s *Limit) SetGeneratedDefaults() {
+	if s.Rate == 0 {
+		s.Rate = 1.5
+	}
+	if s.Burst == 0 {
+		s.Burst = 10
+	}
+	if s.Every == 0 {
+		s.Every = time.Duration(1000000000)
+	}
+}
+
+func isZeroServer(v *Server) bool {
+	return v.Host == "" &&
+		v.Port == 0 &&
+		v.TLS == nil
+}
+
+func isZeroLimit(v *Limit) bool {
+	return v.Rate == 0 &&
+		v.Burst == 0 &&
+		v.Every == 0
+}
+
+func isZeroTLS(v *TLS) bool {
+	return !v.Enabled &&
+		v.Cert == ""
+}
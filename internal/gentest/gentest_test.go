@@ -0,0 +1,72 @@
+package gentest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sidai/defaults"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type GeneratedSuite struct{}
+
+var _ = Suite(&GeneratedSuite{})
+
+// reflective is configured like the package level filler, except it never uses the generated methods
+var reflective = defaults.NewFiller(defaults.UseDefault(), defaults.UseTimeFormat(time.RFC3339), defaults.ParseDuration(),
+	defaults.IgnoreGenerated())
+
+func (s *GeneratedSuite) TestZero(c *C) {
+	var generated, expected Config
+
+	c.Assert(defaults.SetDefaultsE(&generated), IsNil)
+	c.Assert(reflective.SetDefaultsE(&expected), IsNil)
+	c.Assert(generated, DeepEquals, expected)
+	c.Assert(generated.computed, Equals, "app:localhost")
+}
+
+func (s *GeneratedSuite) TestPartial(c *C) {
+	partial := func() Config {
+		retries := 0
+		return Config{
+			Name:     "custom",
+			Retries:  &retries,
+			Tags:     []string{},
+			Labels:   map[string]int{"c": 3},
+			Fallback: Server{Port: 1},
+			Backup:   &Server{Host: "backup"},
+			Replicas: []Server{{}, {Port: 2}},
+			Pointers: []*Server{nil, {Host: "p1"}},
+			Ignored:  []Server{{}},
+			Limits:   map[string]*Limit{"api": nil, "web": {Burst: 1}},
+			Fixed:    [2]Limit{{}, {Rate: 2}},
+			Handler:  &Server{},
+		}
+	}
+	generated, expected := partial(), partial()
+
+	c.Assert(defaults.SetDefaultsE(&generated), IsNil)
+	c.Assert(reflective.SetDefaultsE(&expected), IsNil)
+	c.Assert(generated, DeepEquals, expected)
+	c.Assert(*generated.Retries, Equals, 0)
+	c.Assert(generated.Fixed[0], Equals, Limit{})
+	c.Assert(generated.Pointers[0].TLS.Cert, Equals, "cert.pem")
+}
+
+func (s *GeneratedSuite) TestMethod(c *C) {
+	var generated, expected Server
+
+	generated.SetDefaults()
+	c.Assert(reflective.SetDefaultsE(&expected), IsNil)
+	c.Assert(generated, DeepEquals, expected)
+
+	// SetDefaults of the root type also calls ApplyDefaults
+	var config, expectedConfig Config
+
+	config.SetDefaults()
+	c.Assert(reflective.SetDefaultsE(&expectedConfig), IsNil)
+	c.Assert(config, DeepEquals, expectedConfig)
+	c.Assert(config.computed, Equals, "app:localhost")
+}
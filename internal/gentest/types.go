@@ -0,0 +1,77 @@
+// Package gentest holds the types the SetDefaults methods generated by cmd/defaults-gen are verified against
+package gentest
+
+import "time"
+
+//go:generate go run ../../cmd/defaults-gen -type Config,Server,TLS,Limit -output config_defaults.go
+
+type Role string
+
+type Timeout time.Duration
+
+type IDs []int
+
+type Config struct {
+	Name     string        `default:"app"`
+	Debug    bool          `default:"true"`
+	Workers  int8          `default:"8"`
+	Ratio    float32       `default:"0.75"`
+	Port     uint16        `default:"8080"`
+	Role     Role          `default:"admin"`
+	Interval time.Duration `default:"1m30s"`
+	Timeout  Timeout       `default:"15"`
+	Started  time.Time     `default:"2020-01-02T03:04:05Z"`
+	Quoted   string        `default:"omit"`
+	Empty    string
+
+	Tags    []string          `default:"[a, \"b,c\", ' d ']"`
+	Matrix  [][]int           `default:"[[1,2],[3]]"`
+	IDs     IDs               `default:"[1,2,3]"`
+	NoIDs   []int             `default:"[]"`
+	Pair    [2]int            `default:"[4,5]"`
+	Labels  map[string]int    `default:"{a:1, b:2}"`
+	Roles   map[string][]Role `default:"{\"dev\": [read, write]}"`
+	Raw     []byte            `default:"raw"`
+	NoRaw   []byte
+	Retries *int    `default:"3"`
+	Nothing *string `default:""`
+
+	Server   Server `default:"dive"`
+	Fallback Server
+	Admin    *Server
+	Backup   *Server   `default:"dive"`
+	Skipped  *Server   `default:"omit"`
+	Replicas []Server  `default:"dive"`
+	Pointers []*Server `default:"dive"`
+	Ignored  []Server
+	Limits   map[string]*Limit `default:"dive"`
+	Fixed    [2]Limit
+	Handler  interface{}
+	Callback func()
+	Limit
+
+	computed string
+}
+
+type Server struct {
+	Host string `default:"localhost"`
+	Port int    `default:"80"`
+	TLS  *TLS
+}
+
+type TLS struct {
+	Enabled bool   `default:"true"`
+	Cert    string `default:"cert.pem"`
+}
+
+type Limit struct {
+	Rate  float64       `default:"1.5"`
+	Burst int           `default:"10"`
+	Every time.Duration `default:"1s"`
+}
+
+// ApplyDefaults completes the config once all its fields are filled
+func (c *Config) ApplyDefaults() error {
+	c.computed = c.Name + ":" + c.Server.Host
+	return nil
+}
@@ -0,0 +1,65 @@
+package defaults
+
+import (
+	"errors"
+	"strings"
+)
+
+// LookupFn returns the value of the variable and whether it is set, e.g. os.LookupEnv
+type LookupFn func(name string) (string, bool)
+
+// LookupMap returns a LookupFn reading variables from the given map
+func LookupMap(values map[string]string) LookupFn {
+	return func(name string) (string, bool) {
+		value, ok := values[name]
+		return value, ok
+	}
+}
+
+// interpolate expands ${NAME} and ${NAME:-fallback} with variables from lookup, $$ is expanded to a literal $.
+// An unset variable without fallback expands to an empty string.
+func interpolate(expr string, lookup LookupFn) (string, error) {
+	if !strings.Contains(expr, "$") {
+		return expr, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(expr); i++ {
+		if expr[i] != '$' || i+1 == len(expr) {
+			b.WriteByte(expr[i])
+			continue
+		}
+
+		switch expr[i+1] {
+		case '$':
+			b.WriteByte('$')
+			i++
+		case '{':
+			end := strings.IndexByte(expr[i:], '}')
+			if end < 0 {
+				return "", errors.New("missing closing brace in variable")
+			}
+
+			name, fallback := expr[i+2:i+end], ""
+			hasFallback := false
+			if sep := strings.Index(name, ":-"); sep >= 0 {
+				name, fallback, hasFallback = name[:sep], name[sep+2:], true
+			}
+			if name == "" {
+				return "", errors.New("empty variable name")
+			}
+
+			// like shell, fallback applies to both unset and empty variable
+			if value, ok := lookup(name); ok && (value != "" || !hasFallback) {
+				b.WriteString(value)
+			} else {
+				b.WriteString(fallback)
+			}
+			i += end
+		default:
+			b.WriteByte('$')
+		}
+	}
+
+	return b.String(), nil
+}
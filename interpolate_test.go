@@ -0,0 +1,37 @@
+package defaults
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type InterpolateSuite struct{}
+
+var _ = Suite(&InterpolateSuite{})
+
+func (s *InterpolateSuite) TestInterpolate(c *C) {
+	lookup := LookupMap(map[string]string{"HOME": "/home/app", "HOST": "example.com", "EMPTY": ""})
+
+	fn := func(expr string) string {
+		value, err := interpolate(expr, lookup)
+		c.Assert(err, IsNil)
+		return value
+	}
+
+	c.Assert(fn("plain"), Equals, "plain")
+	c.Assert(fn("${HOME}/.cache/app"), Equals, "/home/app/.cache/app")
+	c.Assert(fn("http://${HOST:-localhost}:8080"), Equals, "http://example.com:8080")
+	c.Assert(fn("http://${MISSING:-localhost}:8080"), Equals, "http://localhost:8080")
+	c.Assert(fn("${EMPTY:-fallback}"), Equals, "fallback")
+	c.Assert(fn("${EMPTY}"), Equals, "")
+	c.Assert(fn("${MISSING}"), Equals, "")
+	c.Assert(fn("${MISSING:-}"), Equals, "")
+	c.Assert(fn("$${HOME}"), Equals, "${HOME}")
+	c.Assert(fn("cost $5$"), Equals, "cost $5$")
+	c.Assert(fn("$$$$"), Equals, "$$")
+
+	_, err := interpolate("${HOME", lookup)
+	c.Assert(err, ErrorMatches, "missing closing brace in variable")
+
+	_, err = interpolate("${:-x}", lookup)
+	c.Assert(err, ErrorMatches, "empty variable name")
+}
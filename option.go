@@ -2,15 +2,61 @@ package defaults
 
 import (
 	"bytes"
+	"fmt"
+	"math"
+	"os"
 	"reflect"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 )
 
 type Option func(filler *filler)
 
+// UseStrict reports malformed slice and map literals, unbalanced nesting, map entries without key value separator
+// and duplicate map keys instead of silently ignoring them
+func UseStrict() Option {
+	return func(f *filler) {
+		f.Strict = true
+	}
+}
+
+// UseMaxDepth allows nil pointers of a self-referential type to be allocated and filled up to depth times,
+// e.g. a linked list of depth+1 nodes. By default, depth is 0 which leaves the pointer nil once a cycle is found.
+func UseMaxDepth(depth int) Option {
+	return func(f *filler) {
+		f.MaxDepth = depth
+	}
+}
+
+// UseEnvTag fills a field from the environment variable named by the tag, e.g. `env:"HTTP_PORT" default:"8080"`,
+// and falls back to the default tag when the variable is not set. The value is parsed exactly like a default tag.
+// The tag of a struct field is also prepended to the variable name of all its nested fields, e.g. `env:"DB_"`.
+func UseEnvTag(tag string) Option {
+	return func(f *filler) {
+		f.EnvTag = tag
+	}
+}
+
+// UseEnvPrefix prepends the prefix to every environment variable looked up via UseEnvTag
+func UseEnvPrefix(prefix string) Option {
+	return func(f *filler) {
+		f.EnvPrefix = prefix
+	}
+}
+
+// UseInterpolation expands variables in default tags before parsing, e.g. `default:"http://${HOST:-localhost}:8080"`.
+// Variables are read from lookup or from the environment when lookup is nil, use $$ for a literal $.
+func UseInterpolation(lookup LookupFn) Option {
+	return func(f *filler) {
+		if lookup == nil {
+			lookup = os.LookupEnv
+		}
+		f.Lookup = lookup
+	}
+}
+
 func UseDefaultTag(tag string) Option {
 	return func(f *filler) {
 		f.DefaultTag = tag
@@ -31,9 +77,14 @@ func UseOmitKey(key string) Option {
 
 func ParseDuration() Option {
 	return func(f *filler) {
+		f.durations = true
 		f.FuncsByKind[reflect.Int64] = f.skipIfTagEmpty(func(field *Field) {
 			if field.Value.Type() == reflect.TypeOf(time.Second) {
-				value, _ := time.ParseDuration(field.Tag)
+				value, err := time.ParseDuration(field.Tag)
+				if err != nil {
+					field.AddError(err)
+					return
+				}
 				field.Value.Set(reflect.ValueOf(value))
 				return
 			}
@@ -44,15 +95,55 @@ func ParseDuration() Option {
 
 func UseTimeFormat(layout string) Option {
 	return func(f *filler) {
-		f.FuncsByType[reflect.TypeOf(time.Time{})] = f.skipIfTagEmpty(func(field *Field) {
+		f.timeLayout = layout
+		f.FuncsByType[timeType] = f.skipIfTagEmpty(func(field *Field) {
 			if field.Value.IsZero() {
-				value, _ := time.Parse(layout, field.Tag)
+				value, err := time.Parse(layout, field.Tag)
+				if err != nil {
+					field.AddError(err)
+					return
+				}
 				field.Value.Set(reflect.ValueOf(value))
 			}
 		})
 	}
 }
 
+// UseKindFunc fills every field of the kind with fn, replacing the fill func registered for the kind if any
+func UseKindFunc(kind reflect.Kind, fn FillFn) Option {
+	return func(f *filler) {
+		f.FuncsByKind[kind] = fn
+		f.customFuncs = true
+	}
+}
+
+// UseTypeFunc fills every field of the exact type with fn, replacing the fill func registered for the type if any.
+// As any other FuncsByType, it runs after FuncsByKind and only when the field still should be filled.
+func UseTypeFunc(typ reflect.Type, fn FillFn) Option {
+	return func(f *filler) {
+		f.FuncsByType[typ] = fn
+		f.customFuncs = true
+		if typ == timeType {
+			f.timeLayout = "" // time is no longer parsed by the layout
+		}
+	}
+}
+
+// UseTypeParser fills every field of type T by parsing its tag with parse, e.g. url.Parse.
+// Like encoding.TextUnmarshaler, it takes precedence over FuncsByKind, and fields with empty tag are skipped.
+func UseTypeParser[T any](parse func(tag string) (T, error)) Option {
+	return func(f *filler) {
+		f.ParsersByType[reflect.TypeOf((*T)(nil)).Elem()] = f.skipIfTagEmpty(func(field *Field) {
+			value, err := parse(field.Tag)
+			if err != nil {
+				field.AddError(err)
+				return
+			}
+			field.Value.Set(reflect.ValueOf(&value).Elem())
+		})
+	}
+}
+
 func UseDefaultType(defVal interface{}) Option {
 	return func(f *filler) {
 		value := reflect.Indirect(reflect.ValueOf(defVal))
@@ -61,12 +152,89 @@ func UseDefaultType(defVal interface{}) Option {
 				reflect.Indirect(field.Value).Set(value)
 			}
 		}
+		f.customFuncs = true
+		if IndirectType(value) == timeType {
+			f.timeLayout = "" // time is no longer parsed by the layout
+		}
+	}
+}
+
+// UseTextUnmarshaler fills types implementing encoding.TextUnmarshaler, e.g. net.IP, by decoding the tag with UnmarshalText.
+// It takes precedence over FuncsByKind but not over FuncsByType registered for the exact same type.
+func UseTextUnmarshaler() Option {
+	return func(f *filler) {
+		f.TextUnmarshaler = true
+	}
+}
+
+// UseJSON decodes default tags prefixed with "json:" as JSON, e.g. `default:"json:{\"name\":\"admin\",\"ports\":[80,443]}"`,
+// which works for any type encoding/json supports including whole structs. Tags without the prefix are parsed as usual.
+func UseJSON() Option {
+	return func(f *filler) {
+		f.JSON = true
+	}
+}
+
+// UseValues fills a field with the value of its key tag, or else of its path, e.g. server.readtimeout or Servers[0].Host,
+// or else of its path in snake case, e.g. SERVER_READ_TIMEOUT or SERVERS_0_HOST as in dotenv files.
+// Keys are case-insensitive and values are parsed exactly like default tags, which they take precedence over,
+// while environment variables of UseEnvTag take precedence over values.
+func UseValues(values map[string]string) Option {
+	return func(f *filler) {
+		f.addValues(values)
+	}
+}
+
+// UseKeyValueFile loads the values of UseValues from a dotenv or properties file, see ParseKeyValues.
+// An error loading the file is returned by every SetDefaultsE, fields are still filled from their tags.
+func UseKeyValueFile(path string) Option {
+	return func(f *filler) {
+		values, err := loadKeyValueFile(path)
+		if err != nil {
+			f.loadErrs = append(f.loadErrs, err)
+			return
+		}
+		f.addValues(values)
+	}
+}
+
+// UseDirectory loads the values of UseValues from a directory with a file per key, e.g. a Kubernetes ConfigMap or Secret mount.
+// A file is named after the key tag or the path of a field, e.g. server.readtimeout or SERVER_READ_TIMEOUT as with UseValues,
+// and its content without trailing newlines is the value. Errors are reported like UseKeyValueFile.
+func UseDirectory(dir string) Option {
+	return func(f *filler) {
+		values, err := loadDirectory(dir)
+		if err != nil {
+			f.loadErrs = append(f.loadErrs, err)
+			return
+		}
+		f.addValues(values)
+	}
+}
+
+// IgnoreGenerated fills every struct by reflection, even when it has methods generated by cmd/defaults-gen.
+// Otherwise, the generated method is used whenever the filler is configured like the package level filler the code is
+// generated for, i.e. UseDefault, UseTimeFormat(time.RFC3339) and ParseDuration without any option changing how tags are parsed,
+// and nothing is reported per field.
+func IgnoreGenerated() Option {
+	return func(f *filler) {
+		f.IgnoreGenerated = true
+	}
+}
+
+// UseByteSizes parses integer tags ending with a size unit as a number of bytes, e.g. `default:"64MiB"` or `default:"1GB"`.
+// Units KB, MB, GB, TB, PB and EB are powers of 1000 while KiB, MiB, GiB, TiB, PiB and EiB are powers of 1024.
+func UseByteSizes() Option {
+	return func(f *filler) {
+		f.ByteSizes = true
 	}
 }
 
 func UseDefault() Option {
 	return func(f *filler) {
 		f.useDefaultKindFuncs()
+		f.durations = false // int64 is parsed as a plain number again
+		f.TextUnmarshaler = true
 	}
 }
 
@@ -74,12 +242,20 @@ func (f *filler) useDefaultKindFuncs() {
 	fns := f.FuncsByKind
 
 	fns[reflect.Bool] = f.skipIfTagEmpty(func(field *Field) {
-		value, _ := strconv.ParseBool(field.Tag)
+		value, err := strconv.ParseBool(field.Tag)
+		if err != nil {
+			field.AddError(err)
+			return
+		}
 		field.Value.SetBool(value)
 	})
 
 	fns[reflect.Int] = f.skipIfTagEmpty(func(field *Field) {
-		value, _ := strconv.ParseInt(field.Tag, 10, 64)
+		value, err := f.parseInt(field)
+		if err != nil {
+			field.AddError(err)
+			return
+		}
 		field.Value.SetInt(value)
 	})
 	fns[reflect.Int8] = fns[reflect.Int]
@@ -88,20 +264,39 @@ func (f *filler) useDefaultKindFuncs() {
 	fns[reflect.Int64] = fns[reflect.Int]
 
 	fns[reflect.Uint] = f.skipIfTagEmpty(func(field *Field) {
-		value, _ := strconv.ParseUint(field.Tag, 10, 64)
+		value, err := f.parseUint(field)
+		if err != nil {
+			field.AddError(err)
+			return
+		}
 		field.Value.SetUint(value)
 	})
 	fns[reflect.Uint8] = fns[reflect.Uint]
 	fns[reflect.Uint16] = fns[reflect.Uint]
 	fns[reflect.Uint32] = fns[reflect.Uint]
 	fns[reflect.Uint64] = fns[reflect.Uint]
+	fns[reflect.Uintptr] = fns[reflect.Uint]
 
 	fns[reflect.Float32] = f.skipIfTagEmpty(func(field *Field) {
-		value, _ := strconv.ParseFloat(field.Tag, 64)
+		value, err := strconv.ParseFloat(field.Tag, field.Value.Type().Bits())
+		if err != nil {
+			field.AddError(err)
+			return
+		}
 		field.Value.SetFloat(value)
 	})
 	fns[reflect.Float64] = fns[reflect.Float32]
 
+	fns[reflect.Complex64] = f.skipIfTagEmpty(func(field *Field) {
+		value, err := strconv.ParseComplex(field.Tag, field.Value.Type().Bits())
+		if err != nil {
+			field.AddError(err)
+			return
+		}
+		field.Value.SetComplex(value)
+	})
+	fns[reflect.Complex128] = fns[reflect.Complex64]
+
 	fns[reflect.String] = f.skipIfTagEmpty(func(field *Field) {
 		field.Value.SetString(field.Tag)
 	})
@@ -113,16 +308,21 @@ func (f *filler) useDefaultKindFuncs() {
 	fns[reflect.Ptr] = func(field *Field) {
 		ptr := field.Value
 
+		// Stop at self-referential data, either a value being filled already or a type nested too deep
+		if f.isCycle(field) {
+			return
+		}
+
 		// Nil Ptr, re-point to address containing a zero value
 		if field.Value.IsNil() {
 			ptr = reflect.New(field.Value.Type().Elem())
 		}
 
-		f.fillField(&Field{
-			Value:  ptr.Elem(),
-			Tag:    field.Tag,
-			Parent: field.Parent,
-		})
+		elem := field.elem(ptr.Elem())
+		if field.Value.IsNil() && elem.nilPtr == nil {
+			elem.nilPtr = field.Value.Interface()
+		}
+		f.fillField(elem)
 
 		if field.Value.IsNil() && !ptr.Elem().IsZero() {
 			field.Value.Set(ptr)
@@ -132,32 +332,34 @@ func (f *filler) useDefaultKindFuncs() {
 	fns[reflect.Interface] = func(field *Field) {
 		// Only set default value for the interface if underlying implementation is of kind struct and not nil
 		if !field.Value.IsNil() && GetValueInternalKind(field.Value) == reflect.Struct {
-			f.fillField(&Field{
-				Value:  field.Value.Elem(),
-				Tag:    field.Tag,
-				Parent: field.Parent,
-			})
+			f.fillField(field.elem(field.Value.Elem()))
 		}
 	}
 
 	fns[reflect.Slice] = func(field *Field) {
-		switch GetValueInternalKind(field.Value) {
-		case reflect.Uint8:
+		switch {
+		case field.Value.Type().Elem().Kind() == reflect.Uint8:
 			if field.Value.Bytes() == nil {
 				field.Value.SetBytes([]byte(field.Tag))
 			}
-		case reflect.Struct, reflect.Interface:
+		case f.isStructContainer(field):
 			for i := 0; i < field.Value.Len(); i++ {
 				f.fillField(&Field{
-					Value:  field.Value.Index(i),
-					Tag:    field.Tag,
-					Parent: field,
+					Value:   field.Value.Index(i),
+					Tag:     field.Tag,
+					Parent:  field,
+					Index:   i,
+					segment: indexSegment(reflect.ValueOf(i)),
 				})
 			}
 		default:
 			// handle slice of data with eventually primitive type like [1,2,3,4], [[1,2], [3,4]], [{1:2},{3:4}]
 			tag, slice := field.Tag, field.Value
-			if !regexp.MustCompile(`^\[.*]$`).MatchString(tag) {
+			if f.loadLiteral(field) {
+				return
+			}
+			if !sliceLiteral.MatchString(tag) {
+				f.strictError(field, fmt.Errorf("%w: slice value must be enclosed in []", ErrMalformedLiteral))
 				return // invalid default value to set slice
 			}
 
@@ -166,29 +368,106 @@ func (f *filler) useDefaultKindFuncs() {
 				return
 			}
 
-			values := tokenizeValues(tag[1 : len(tag)-1])
+			values, err := f.tokenizeLiteral(field, tag[1:len(tag)-1])
+			if err != nil && f.isStrict(field) {
+				field.AddError(err)
+				return
+			}
+
+			errCount := field.errCount()
 			result := reflect.MakeSlice(slice.Type(), len(values), len(values))
 			for i := 0; i < len(values); i++ {
 				f.fillField(&Field{
-					Value:  result.Index(i),
-					Tag:    values[i],
-					Parent: field,
+					Value:   result.Index(i),
+					Tag:     unquoteToken(values[i]),
+					Parent:  field,
+					Index:   i,
+					segment: indexSegment(reflect.ValueOf(i)),
 				})
 			}
+			if f.rejectLiteral(field, errCount) {
+				return
+			}
 			slice.Set(result)
+			f.storeLiteral(field, errCount)
+		}
+	}
+
+	fns[reflect.Array] = func(field *Field) {
+		switch {
+		case f.isStructContainer(field):
+			for i := 0; i < field.Value.Len(); i++ {
+				f.fillField(&Field{
+					Value:   field.Value.Index(i),
+					Tag:     field.Tag,
+					Parent:  field,
+					Index:   i,
+					segment: indexSegment(reflect.ValueOf(i)),
+				})
+			}
+		default:
+			// handle array of data with eventually primitive type like [1,2,3,4], [[1,2], [3,4]], [{1:2},{3:4}]
+			// raw bytes are copied for byte array like [16]byte without literal
+			tag, array := field.Tag, field.Value
+			if array.Type().Elem().Kind() == reflect.Uint8 && !strings.HasPrefix(tag, "[") {
+				if len(tag) != array.Len() {
+					f.strictError(field, fmt.Errorf("%w: got %d bytes for array of length %d", ErrMalformedLiteral, len(tag), array.Len()))
+				}
+				reflect.Copy(array, reflect.ValueOf([]byte(tag)))
+				return
+			}
+
+			if f.loadLiteral(field) {
+				return
+			}
+			parseErrs := field.errCount()
+			if !sliceLiteral.MatchString(tag) {
+				f.strictError(field, fmt.Errorf("%w: array value must be enclosed in []", ErrMalformedLiteral))
+				return // invalid default value to set array
+			}
+
+			values, err := f.tokenizeLiteral(field, tag[1:len(tag)-1])
+			if err != nil && f.isStrict(field) {
+				field.AddError(err)
+				return
+			}
+
+			// fill as many elements as possible, the rest are either left zero or dropped
+			if len(values) != array.Len() {
+				f.strictError(field, fmt.Errorf("%w: got %d elements for array of length %d", ErrMalformedLiteral, len(values), array.Len()))
+			}
+
+			errCount := field.errCount()
+			result := reflect.New(array.Type()).Elem()
+			for i := 0; i < len(values) && i < result.Len(); i++ {
+				f.fillField(&Field{
+					Value:   result.Index(i),
+					Tag:     unquoteToken(values[i]),
+					Parent:  field,
+					Index:   i,
+					segment: indexSegment(reflect.ValueOf(i)),
+				})
+			}
+			if f.rejectLiteral(field, errCount) {
+				return
+			}
+			array.Set(result)
+			f.storeLiteral(field, parseErrs)
 		}
 	}
 
 	fns[reflect.Map] = func(field *Field) {
-		switch GetValueInternalKind(field.Value) {
-		case reflect.Struct, reflect.Interface:
+		switch {
+		case f.isStructContainer(field):
 			// Map kind has both unaddressable key and value, we can only directly set the map using SetMapIndex
 			for _, mapKay := range field.Value.MapKeys() {
 				mapVal := field.Value.MapIndex(mapKay)
 				item := &Field{
-					Value:  reflect.New(mapVal.Type()).Elem(),
-					Tag:    field.Tag,
-					Parent: field,
+					Value:   reflect.New(mapVal.Type()).Elem(),
+					Tag:     field.Tag,
+					Parent:  field,
+					Key:     mapKay,
+					segment: indexSegment(mapKay),
 				}
 				item.Value.Set(mapVal) // copy the original value before set the rest
 				f.fillField(item)
@@ -197,7 +476,11 @@ func (f *filler) useDefaultKindFuncs() {
 		default:
 			// handle slice of data with eventually primitive type like {1:2, 3:4}, {"arr":[1,2,3]} {1: {1:2}, 2: {3:4}}
 			tag, mapField := field.Tag, field.Value
-			if !regexp.MustCompile(`^{.*}$`).MatchString(tag) {
+			if f.loadLiteral(field) {
+				return
+			}
+			if !mapLiteral.MatchString(tag) {
+				f.strictError(field, fmt.Errorf("%w: map value must be enclosed in {}", ErrMalformedLiteral))
 				return // invalid default value to set map
 			}
 
@@ -206,33 +489,80 @@ func (f *filler) useDefaultKindFuncs() {
 				return
 			}
 
-			keyValues := tokenizeValues(tag[1 : len(tag)-1])
-			mapField.Set(reflect.MakeMapWithSize(mapField.Type(), len(keyValues)))
+			keyValues, err := f.tokenizeLiteral(field, tag[1:len(tag)-1])
+			if err != nil && f.isStrict(field) {
+				field.AddError(err)
+				return
+			}
+
+			errCount := field.errCount()
+			result := reflect.MakeMapWithSize(mapField.Type(), len(keyValues))
 			keyType := mapField.Type().Key()
 			valType := mapField.Type().Elem()
 
 			for _, entry := range keyValues {
-				keyValue := strings.SplitN(entry, ":", 2)
-				if len(keyValue) < 2 {
+				key, value, ok := splitKeyValue(entry)
+				if !ok {
+					f.strictError(field, fmt.Errorf("%w: missing ':' in map entry %q", ErrMalformedLiteral, entry))
 					continue
 				}
+				key, value = unquoteToken(key), unquoteToken(value)
 
+				// The key is named like its value field even before it is parsed, e.g. ["api"] or [1]
+				keySegment := "[" + key + "]"
+				if keyType.Kind() == reflect.String {
+					keySegment = indexSegment(reflect.ValueOf(key))
+				}
 				keyField := &Field{
-					Value:  reflect.New(keyType).Elem(),
-					Tag:    keyValue[0],
-					Parent: field,
+					Value:   reflect.New(keyType).Elem(),
+					Tag:     key,
+					Parent:  field,
+					segment: keySegment,
 				}
 				f.fillField(keyField)
 
 				valField := &Field{
-					Value:  reflect.New(valType).Elem(),
-					Tag:    keyValue[1],
-					Parent: field,
+					Value:   reflect.New(valType).Elem(),
+					Tag:     value,
+					Parent:  field,
+					Key:     keyField.Value,
+					segment: indexSegment(keyField.Value),
 				}
 				f.fillField(valField)
 
-				mapField.SetMapIndex(keyField.Value, valField.Value)
+				if result.MapIndex(keyField.Value).IsValid() {
+					f.strictError(keyField, ErrDuplicateKey)
+				}
+				result.SetMapIndex(keyField.Value, valField.Value)
+			}
+
+			if f.rejectLiteral(field, errCount) {
+				return
 			}
+			mapField.Set(result)
+			f.storeLiteral(field, errCount)
+		}
+	}
+}
+
+// isStructContainer tells whether the slice, array or map field holds structs or interfaces to fill one by one,
+// rather than values parsed from a literal, e.g. []Struct compared to []url.URL with a registered parser
+func (f *filler) isStructContainer(field *Field) bool {
+	if kind := GetValueInternalKind(field.Value); kind != reflect.Struct && kind != reflect.Interface {
+		return false
+	}
+
+	for typ := field.Value.Type(); ; typ = typ.Elem() {
+		if _, ok := f.ParsersByType[typ]; ok {
+			return false
+		}
+		if f.TextUnmarshaler && reflect.PtrTo(typ).Implements(textUnmarshalerType) {
+			return false
+		}
+		switch typ.Kind() {
+		case reflect.Slice, reflect.Array, reflect.Map, reflect.Ptr:
+		default:
+			return true
 		}
 	}
 }
@@ -245,35 +575,238 @@ func (f *filler) skipIfTagEmpty(fn FillFn) FillFn {
 	}
 }
 
-func tokenizeValues(expr string) []string {
+// parseInt parses the tag with the size of the field in any Go integer literal syntax, e.g. 0x1F, 0o17, 0b11 or 1_000,
+// or as a byte size when enabled
+func (f *filler) parseInt(field *Field) (int64, error) {
+	bits := field.Value.Type().Bits()
+	if size, ok, err := f.parseByteSize(field.Tag); ok {
+		if err == nil && size > 1<<(bits-1)-1 {
+			err = &strconv.NumError{Func: "ParseInt", Num: field.Tag, Err: strconv.ErrRange}
+		}
+		return int64(size), err
+	}
+
+	return strconv.ParseInt(field.Tag, 0, bits)
+}
+
+// parseUint works like parseInt, except os.FileMode which is octal unless prefixed otherwise, e.g. 0644 or 755
+func (f *filler) parseUint(field *Field) (uint64, error) {
+	bits := field.Value.Type().Bits()
+	if size, ok, err := f.parseByteSize(field.Tag); ok {
+		if err == nil && bits < 64 && size >= 1<<bits {
+			err = &strconv.NumError{Func: "ParseUint", Num: field.Tag, Err: strconv.ErrRange}
+		}
+		return size, err
+	}
+
+	base := 0
+	if field.Value.Type() == fileModeType && !hasBasePrefix(field.Tag) {
+		base = 8
+	}
+	return strconv.ParseUint(field.Tag, base, bits)
+}
+
+func hasBasePrefix(tag string) bool {
+	if len(tag) < 2 || tag[0] != '0' {
+		return false
+	}
+	switch tag[1] {
+	case 'x', 'X', 'o', 'O', 'b', 'B':
+		return true
+	default:
+		return false
+	}
+}
+
+// byteUnits lists the size units by the length of their suffix, so B is only matched once the others are not
+var byteUnits = []struct {
+	suffix string
+	size   uint64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40}, {"PiB", 1 << 50}, {"EiB", 1 << 60},
+	{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12}, {"PB", 1e15}, {"EB", 1e18},
+	{"B", 1},
+}
+
+// parseByteSize parses the tag as a decimal number followed by a size unit, ok is false when the tag has no unit
+// or byte sizes are disabled. Any other number is left to integer parsing, e.g. 0x1B is 27 rather than 1 byte.
+func (f *filler) parseByteSize(tag string) (size uint64, ok bool, err error) {
+	if !f.ByteSizes {
+		return 0, false, nil
+	}
+
+	for _, unit := range byteUnits {
+		num := strings.TrimSpace(strings.TrimSuffix(tag, unit.suffix))
+		if num == tag || num == "" || strings.TrimLeft(num, "0123456789") != "" {
+			continue
+		}
+
+		n, err := strconv.ParseUint(num, 10, 64)
+		if err != nil {
+			return 0, true, err
+		}
+		if n > math.MaxUint64/unit.size {
+			return 0, true, &strconv.NumError{Func: "ParseUint", Num: tag, Err: strconv.ErrRange}
+		}
+		return n * unit.size, true, nil
+	}
+
+	return 0, false, nil
+}
+
+// rejectLiteral tells whether a parsed literal should be dropped in strict mode,
+// i.e. any of its elements failed since errCount errors were reported
+func (f *filler) rejectLiteral(field *Field, errCount int) bool {
+	return f.isStrict(field) && field.errCount() > errCount
+}
+
+// strictError reports the error only when strict mode is enabled
+func (f *filler) strictError(field *Field, err error) {
+	if f.isStrict(field) && field.Tag != "" {
+		field.AddError(err)
+	}
+}
+
+// isStrict tells whether malformed literals are reported, either by UseStrict or for this filling only
+func (f *filler) isStrict(field *Field) bool {
+	if root := field.root(); root.state != nil && root.state.strict {
+		return true
+	}
+	return f.Strict
+}
+
+// tokenizeValues splits the content of a slice or map literal by its top level comma and trims the space around tokens.
+// Comma, colon and brackets are kept as is inside single or double quotes, where backslash escapes the next character.
+// A quote only opens at the start of a token or a nested value, any other quote is an ordinary character, e.g. don't.
+// Tokens are still returned for unbalanced nesting or unterminated quote together with an error.
+func tokenizeValues(expr string) ([]string, error) {
 	var tokens []string
 
 	var count int
+	var quote, last rune
+	var escaped bool
+	var err error
 	buf := bytes.NewBufferString("")
 
 	for _, b := range []rune(expr) {
-		switch b {
-		case ',':
+		switch {
+		case escaped:
+			escaped = false
+			buf.WriteRune(b)
+		case quote != 0:
+			if b == '\\' {
+				escaped = true
+			} else if b == quote {
+				quote = 0
+			}
+			buf.WriteRune(b)
+		case opensQuote(b, last):
+			quote = b
+			buf.WriteRune(b)
+		case b == ',':
 			if count == 0 { // end of value
-				tokens = append(tokens, buf.String())
+				tokens = append(tokens, strings.TrimSpace(buf.String()))
 				buf.Reset()
+				last = 0
 				continue
 			}
 			buf.WriteRune(b)
-		case '{', '[':
+		case b == '{' || b == '[':
 			count++
 			buf.WriteRune(b)
-		case '}', ']':
+		case b == '}' || b == ']':
 			count--
+			if count < 0 {
+				err = fmt.Errorf("%w: unexpected %q", ErrMalformedLiteral, b)
+			}
 			buf.WriteRune(b)
 		default:
 			buf.WriteRune(b)
 		}
+		if !unicode.IsSpace(b) {
+			last = b
+		}
 	}
 
-	if buf.Len() > 0 {
-		tokens = append(tokens, buf.String())
+	if token := strings.TrimSpace(buf.String()); token != "" {
+		tokens = append(tokens, token)
+	}
+
+	if err == nil && quote != 0 {
+		err = fmt.Errorf("%w: missing closing quote %q", ErrMalformedLiteral, quote)
+	}
+	if err == nil && count > 0 {
+		err = fmt.Errorf("%w: missing closing bracket", ErrMalformedLiteral)
+	}
+
+	return tokens, err
+}
+
+// splitKeyValue splits a map entry by its first colon outside quotes and brackets, e.g. "http://a:80":[1,2]
+func splitKeyValue(entry string) (string, string, bool) {
+	var count int
+	var quote, last rune
+	var escaped bool
+
+	for i, b := range entry {
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0:
+			if b == '\\' {
+				escaped = true
+			} else if b == quote {
+				quote = 0
+			}
+		case opensQuote(b, last):
+			quote = b
+		case b == '{' || b == '[':
+			count++
+		case b == '}' || b == ']':
+			count--
+		case b == ':' && count == 0:
+			return strings.TrimSpace(entry[:i]), strings.TrimSpace(entry[i+1:]), true
+		}
+		if !unicode.IsSpace(b) {
+			last = b
+		}
+	}
+
+	return entry, "", false
+}
+
+// opensQuote tells whether the character starts a quoted value, which it only does at the start of a token,
+// i.e. after last being the previous character other than space, so that apostrophes in bare tokens like don't are kept
+func opensQuote(b, last rune) bool {
+	return (b == '"' || b == '\'') && (last == 0 || strings.ContainsRune("[{,:", last))
+}
+
+// unquoteToken removes the quotes around a token and resolves its escapes, any other token is returned as is
+func unquoteToken(token string) string {
+	if len(token) < 2 || (token[0] != '"' && token[0] != '\'') || token[len(token)-1] != token[0] {
+		return token
+	}
+
+	var b strings.Builder
+	var escaped bool
+	for _, r := range token[1 : len(token)-1] {
+		if !escaped && r == '\\' {
+			escaped = true
+			continue
+		}
+		if escaped {
+			switch r {
+			case 'n':
+				r = '\n'
+			case 't':
+				r = '\t'
+			case 'r':
+				r = '\r'
+			}
+			escaped = false
+		}
+		b.WriteRune(r)
 	}
 
-	return tokens
+	return b.String()
 }
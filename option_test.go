@@ -1,7 +1,16 @@
 package defaults
 
 import (
+	"errors"
+	"fmt"
 	. "gopkg.in/check.v1"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -145,3 +154,392 @@ func (s *OptionSuite) TestUseDefaultType(c *C) {
 	c.Assert(foo.StructWithValueDive, Equals, DefaultStruct{Integer: 1, String: ""})
 	c.Assert(foo.StructList[0], Equals, DefaultStruct{Integer: 1, String: ""})
 }
+
+type ExampleStrict struct {
+	IntList          []int   `default:"[1,2,3]"`
+	IntListInvalid   []int   `default:"1,2,3"`
+	IntListUnbalance [][]int `default:"[[1,2],[3]"`
+	IntListNoTag     []int
+	Map              map[int]string   `default:"{1:a,2:b}"`
+	MapInvalid       map[int]string   `default:"1:a"`
+	MapUnbalance     map[int][]string `default:"{1:[a],2:b]}"`
+	MapNoSeparator   map[int]string   `default:"{1:a,2}"`
+	MapDuplicateKey  map[int]string   `default:"{1:a,1:b}"`
+}
+
+func (s *OptionSuite) TestUseStrict(c *C) {
+	var foo ExampleStrict
+
+	err := NewFiller(UseDefault()).SetDefaultsE(&foo)
+
+	c.Assert(err, IsNil)
+	c.Assert(foo.IntListInvalid, IsNil)
+	c.Assert(foo.MapNoSeparator, DeepEquals, map[int]string{1: "a"})
+	c.Assert(foo.MapDuplicateKey, DeepEquals, map[int]string{1: "b"})
+
+	var bar ExampleStrict
+
+	err = NewFiller(UseDefault(), UseStrict()).SetDefaultsE(&bar)
+
+	var msgs []string
+	for _, e := range err.(Errors) {
+		c.Assert(errors.Is(e, ErrMalformedLiteral) || errors.Is(e, ErrDuplicateKey), Equals, true)
+		msgs = append(msgs, e.(*FieldError).Path+": "+e.(*FieldError).Err.Error())
+	}
+	c.Assert(msgs, DeepEquals, []string{
+		"IntListInvalid: malformed literal: slice value must be enclosed in []",
+		"IntListUnbalance: malformed literal: missing closing bracket",
+		"MapInvalid: malformed literal: map value must be enclosed in {}",
+		"MapUnbalance: malformed literal: unexpected ']'",
+		`MapNoSeparator: malformed literal: missing ':' in map entry "2"`,
+		"MapDuplicateKey[1]: duplicate map key",
+	})
+	c.Assert(bar.IntList, DeepEquals, []int{1, 2, 3})
+	c.Assert(bar.IntListUnbalance, IsNil)
+	c.Assert(bar.Map, DeepEquals, map[int]string{1: "a", 2: "b"})
+	c.Assert(bar.MapUnbalance, IsNil)
+	// literals are dropped once any of their entries fails
+	c.Assert(bar.MapNoSeparator, IsNil)
+	c.Assert(bar.MapDuplicateKey, IsNil)
+}
+
+type Node struct {
+	Val  int `default:"1"`
+	Next *Node
+}
+
+type Tree struct {
+	Name  string `default:"tree"`
+	Left  *Tree
+	Right *Tree
+	Root  *TreeRoot `default:"dive"`
+}
+
+type TreeRoot struct {
+	Tree *Tree `default:"dive"`
+}
+
+func (s *OptionSuite) TestMaxDepth(c *C) {
+	var foo Node
+
+	SetDefaults(&foo)
+
+	c.Assert(foo, DeepEquals, Node{Val: 1})
+
+	var bar Node
+
+	NewFiller(UseDefault(), UseMaxDepth(2)).SetDefaults(&bar)
+
+	c.Assert(bar, DeepEquals, Node{Val: 1, Next: &Node{Val: 1, Next: &Node{Val: 1}}})
+
+	var tree Tree
+
+	NewFiller(UseDefault(), UseMaxDepth(1)).SetDefaults(&tree)
+
+	c.Assert(tree.Name, Equals, "tree")
+	c.Assert(*tree.Left, DeepEquals, Tree{Name: "tree"})
+	c.Assert(*tree.Right, DeepEquals, Tree{Name: "tree"})
+	c.Assert(tree.Root.Tree.Name, Equals, "tree")
+	c.Assert(tree.Root.Tree.Left, IsNil)
+
+	var defaultTree Tree
+
+	SetDefaults(&defaultTree)
+
+	c.Assert(defaultTree, DeepEquals, Tree{Name: "tree"})
+}
+
+func (s *OptionSuite) TestPointerCycle(c *C) {
+	foo := Tree{Root: &TreeRoot{}}
+	foo.Root.Tree = &foo
+
+	SetDefaults(&foo)
+
+	c.Assert(foo.Name, Equals, "tree")
+	c.Assert(foo.Root.Tree, Equals, &foo)
+}
+
+type Level int
+
+func (l *Level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "debug":
+		*l = -1
+	case "info":
+		*l = 0
+	case "error":
+		*l = 1
+	default:
+		return fmt.Errorf("unknown level %q", text)
+	}
+	return nil
+}
+
+type ExampleTextUnmarshaler struct {
+	IP           net.IP            `default:"127.0.0.1"`
+	IPPtr        *net.IP           `default:"::1"`
+	IPList       []net.IP          `default:"[10.0.0.1,10.0.0.2]"`
+	IPMap        map[string]net.IP `default:"{local:127.0.0.1}"`
+	IPWithValue  net.IP            `default:"127.0.0.1"`
+	Level        Level             `default:"error"`
+	LevelNoTag   Level
+	LevelInvalid Level `default:"fatal"`
+}
+
+func (s *OptionSuite) TestUseTextUnmarshaler(c *C) {
+	foo := ExampleTextUnmarshaler{IPWithValue: net.IPv4(8, 8, 8, 8)}
+
+	err := SetDefaultsE(&foo)
+
+	c.Assert(err, ErrorMatches, `defaults: LevelInvalid \(defaults.Level\): invalid default "fatal": unknown level "fatal"`)
+	c.Assert(foo.IP.String(), Equals, "127.0.0.1")
+	c.Assert(foo.IPPtr.String(), Equals, "::1")
+	c.Assert(foo.IPList, DeepEquals, []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")})
+	c.Assert(foo.IPMap, DeepEquals, map[string]net.IP{"local": net.ParseIP("127.0.0.1")})
+	c.Assert(foo.IPWithValue.String(), Equals, "8.8.8.8")
+	c.Assert(foo.Level, Equals, Level(1))
+	c.Assert(foo.LevelNoTag, Equals, Level(0))
+	c.Assert(foo.LevelInvalid, Equals, Level(0))
+
+	var bar ExampleTextUnmarshaler
+
+	NewFiller(UseDefault(), UseDefaultType(Level(7))).SetDefaults(&bar)
+
+	// fill func registered by type takes precedence over UnmarshalText
+	c.Assert(bar.Level, Equals, Level(7))
+	c.Assert(bar.IP.String(), Equals, "127.0.0.1")
+}
+
+type ExampleEnv struct {
+	Port     int           `env:"HTTP_PORT" default:"8080"`
+	Host     string        `env:"HTTP_HOST" default:"localhost"`
+	Timeout  time.Duration `env:"TIMEOUT" default:"1s"`
+	Hosts    []string      `env:"HOSTS"`
+	NoEnv    string        `default:"default"`
+	Database Database      `env:"DB_"`
+	Replica  *Database     `env:"REPLICA_"`
+	Omitted  Database      `env:"OMITTED_" default:"omit"`
+}
+
+type Database struct {
+	Host string `env:"HOST" default:"db"`
+	Port int    `env:"PORT" default:"5432"`
+}
+
+func (s *OptionSuite) TestUseEnvTag(c *C) {
+	env := map[string]string{
+		"APP_HTTP_PORT":    "9090",
+		"APP_TIMEOUT":      "invalid",
+		"APP_HOSTS":        "[a,b]",
+		"APP_DB_HOST":      "postgres",
+		"APP_REPLICA_PORT": "5433",
+		"HTTP_HOST":        "example.com",
+		"APP_NO_ENV":       "env",
+		// variables named like a prefix never replace the dive or omit key
+		"APP_OMITTED_": "dive",
+		"APP_REPLICA_": "omit",
+	}
+	for key, value := range env {
+		os.Setenv(key, value)
+	}
+	defer func() {
+		for key := range env {
+			os.Unsetenv(key)
+		}
+	}()
+
+	var foo ExampleEnv
+
+	err := NewFiller(UseDefault(), ParseDuration(), UseEnvTag("env"), UseEnvPrefix("APP_")).SetDefaultsE(&foo)
+
+	c.Assert(err, ErrorMatches, `defaults: Timeout \(time.Duration\): invalid default "invalid": .*`)
+	c.Assert(foo.Port, Equals, 9090)
+	c.Assert(foo.Host, Equals, "localhost")
+	c.Assert(foo.Timeout, Equals, time.Duration(0))
+	c.Assert(foo.Hosts, DeepEquals, []string{"a", "b"})
+	c.Assert(foo.NoEnv, Equals, "default")
+	c.Assert(foo.Database, Equals, Database{Host: "postgres", Port: 5432})
+	c.Assert(*foo.Replica, Equals, Database{Host: "db", Port: 5433})
+	c.Assert(foo.Omitted, Equals, Database{})
+
+	var bar ExampleEnv
+
+	SetDefaults(&bar)
+
+	c.Assert(bar.Port, Equals, 8080)
+	c.Assert(bar.Database, Equals, Database{Host: "db", Port: 5432})
+}
+
+type ExampleInterpolation struct {
+	Cache   string   `default:"${HOME}/.cache/app"`
+	URL     string   `default:"http://${HOST:-localhost}:8080"`
+	Port    int      `default:"${PORT:-8080}"`
+	Hosts   []string `default:"[${HOST},backup]"`
+	Price   string   `default:"$$5"`
+	Invalid string   `default:"${HOST"`
+}
+
+func (s *OptionSuite) TestUseInterpolation(c *C) {
+	var foo ExampleInterpolation
+
+	lookup := LookupMap(map[string]string{"HOME": "/home/app", "HOST": "example.com"})
+	err := NewFiller(UseDefault(), UseInterpolation(lookup)).SetDefaultsE(&foo)
+
+	c.Assert(err, ErrorMatches, `defaults: Invalid \(string\): invalid default "\${HOST": missing closing brace in variable`)
+	c.Assert(foo.Cache, Equals, "/home/app/.cache/app")
+	c.Assert(foo.URL, Equals, "http://example.com:8080")
+	c.Assert(foo.Port, Equals, 8080)
+	c.Assert(foo.Hosts, DeepEquals, []string{"example.com", "backup"})
+	c.Assert(foo.Price, Equals, "$5")
+	c.Assert(foo.Invalid, Equals, "")
+
+	var bar ExampleInterpolation
+
+	SetDefaults(&bar)
+
+	c.Assert(bar.Cache, Equals, "${HOME}/.cache/app")
+}
+
+type ExampleJSON struct {
+	List       []string         `default:"json:[\"a,b\",\"c\"]"`
+	Array      [3]int           `default:"json:[1,2]"`
+	Map        map[string][]int `default:"json:{\"a\":[1,2],\"b\":[]}"`
+	Struct     DefaultStruct    `default:"json:{\"Integer\":7,\"String\":\"7\"}"`
+	StructPtr  *DefaultStruct   `default:"json:{\"Integer\":7}"`
+	StructList []DefaultStruct  `default:"json:[{\"Integer\":1},{\"Integer\":2}]"`
+	Legacy     map[int]string   `default:"{1:a}"`
+	WithValue  []string         `default:"json:[\"a\"]"`
+	Invalid    map[string]int   `default:"json:{\"a\":\"b\"}"`
+}
+
+func (s *OptionSuite) TestUseJSON(c *C) {
+	foo := ExampleJSON{WithValue: []string{"value"}}
+
+	err := NewFiller(UseDefault(), UseJSON()).SetDefaultsE(&foo)
+
+	c.Assert(err, ErrorMatches, `defaults: Invalid \(map\[string\]int\): invalid default "json:{\\"a\\":\\"b\\"}": json: cannot unmarshal .*`)
+	c.Assert(foo.List, DeepEquals, []string{"a,b", "c"})
+	c.Assert(foo.Array, Equals, [3]int{1, 2, 0})
+	c.Assert(foo.Map, DeepEquals, map[string][]int{"a": {1, 2}, "b": {}})
+	c.Assert(foo.Struct, Equals, DefaultStruct{Integer: 7, String: "7"})
+	c.Assert(*foo.StructPtr, Equals, DefaultStruct{Integer: 7})
+	c.Assert(foo.StructList, DeepEquals, []DefaultStruct{{Integer: 1}, {Integer: 2}})
+	c.Assert(foo.Legacy, DeepEquals, map[int]string{1: "a"})
+	c.Assert(foo.WithValue, DeepEquals, []string{"value"})
+	c.Assert(foo.Invalid, IsNil)
+
+	var bar ExampleJSON
+
+	SetDefaults(&bar)
+
+	c.Assert(bar.List, IsNil)
+	c.Assert(bar.Legacy, DeepEquals, map[int]string{1: "a"})
+}
+
+type ExampleCustomFunc struct {
+	URL        url.URL   `default:"https://example.com:8080/path"`
+	URLPtr     *url.URL  `default:"https://example.com"`
+	URLInvalid url.URL   `default:"://"`
+	URLList    []url.URL `default:"[https://a.com,https://b.com]"`
+	BigInt     *big.Int  `default:"123456789012345678901234567890"`
+	Level      Level     `default:"7"`
+	Upper      string    `default:"upper"`
+	Seed       DefaultStruct
+	SeedOmit   DefaultStruct `default:"omit"`
+}
+
+func (s *OptionSuite) TestUseCustomFunc(c *C) {
+	var foo ExampleCustomFunc
+
+	filler := NewFiller(
+		UseDefault(),
+		UseTypeParser(func(tag string) (url.URL, error) {
+			u, err := url.Parse(tag)
+			if err != nil {
+				return url.URL{}, err
+			}
+			return *u, nil
+		}),
+		UseTypeParser(url.Parse),
+		UseTypeParser(func(tag string) (*big.Int, error) {
+			value, ok := new(big.Int).SetString(tag, 10)
+			if !ok {
+				return nil, fmt.Errorf("invalid big.Int %q", tag)
+			}
+			return value, nil
+		}),
+		UseTypeParser(func(tag string) (Level, error) {
+			value, err := strconv.Atoi(tag)
+			return Level(value * 10), err
+		}),
+		UseKindFunc(reflect.String, func(field *Field) {
+			field.Value.SetString(strings.ToUpper(field.Tag))
+		}),
+		UseTypeFunc(reflect.TypeOf(DefaultStruct{}), func(field *Field) {
+			field.Value.Set(reflect.ValueOf(DefaultStruct{Integer: len(field.StructField.Name)}))
+		}),
+	)
+	err := filler.SetDefaultsE(&foo)
+
+	c.Assert(err, ErrorMatches, `defaults: URLInvalid \(url.URL\): invalid default "://": parse "://": missing protocol scheme`)
+	c.Assert(foo.URL.String(), Equals, "https://example.com:8080/path")
+	c.Assert(foo.URLPtr.String(), Equals, "https://example.com")
+	c.Assert(foo.URLInvalid, Equals, url.URL{})
+	c.Assert(foo.URLList, HasLen, 2)
+	c.Assert(foo.URLList[1].Host, Equals, "b.com")
+	c.Assert(foo.BigInt.String(), Equals, "123456789012345678901234567890")
+	// parser takes precedence over kind, so the tag is not parsed as an integer
+	c.Assert(foo.Level, Equals, Level(70))
+	c.Assert(foo.Upper, Equals, "UPPER")
+	// type func follows the usual zero check and omit key
+	c.Assert(foo.Seed, Equals, DefaultStruct{Integer: 4})
+	c.Assert(foo.SeedOmit, Equals, DefaultStruct{})
+}
+
+type ExampleByteSize struct {
+	Buffer   int           `default:"64MiB"`
+	Disk     uint64        `default:"1GB"`
+	Bytes    uint16        `default:"512B"`
+	Spaced   int           `default:"4 KiB"`
+	Plain    int           `default:"1_024"`
+	Overflow uint16        `default:"64KiB"`
+	Huge     int64         `default:"16EiB"`
+	Invalid  int           `default:"1.5GB"`
+	Hex      int           `default:"0x1B"`
+	HexShort uint16        `default:"0xAB"`
+	Sizes    []int         `default:"[1KB, 1KiB]"`
+	Timeout  time.Duration `default:"1m"`
+}
+
+func (s *OptionSuite) TestUseByteSizes(c *C) {
+	var foo ExampleByteSize
+
+	err := NewFiller(UseDefault(), UseTimeFormat(time.RFC3339), ParseDuration(), UseByteSizes()).SetDefaultsE(&foo)
+
+	var paths []string
+	for _, e := range err.(Errors) {
+		paths = append(paths, e.(*FieldError).Path)
+	}
+	c.Assert(paths, DeepEquals, []string{"Overflow", "Huge", "Invalid"})
+	c.Assert(errors.Is(err, strconv.ErrRange), Equals, true)
+	c.Assert(foo.Buffer, Equals, 64<<20)
+	c.Assert(foo.Disk, Equals, uint64(1e9))
+	c.Assert(foo.Bytes, Equals, uint16(512))
+	c.Assert(foo.Spaced, Equals, 4096)
+	c.Assert(foo.Plain, Equals, 1024)
+	c.Assert(foo.Overflow, Equals, uint16(0))
+	c.Assert(foo.Huge, Equals, int64(0))
+	c.Assert(foo.Invalid, Equals, 0)
+	// hex digits are never taken for a unit
+	c.Assert(foo.Hex, Equals, 27)
+	c.Assert(foo.HexShort, Equals, uint16(171))
+	c.Assert(foo.Sizes, DeepEquals, []int{1000, 1024})
+	c.Assert(foo.Timeout, Equals, time.Minute)
+
+	// sizes are only parsed once enabled
+	var bar ExampleByteSize
+
+	c.Assert(NewFiller(UseDefault()).SetDefaultsE(&bar), NotNil)
+	c.Assert(bar.Buffer, Equals, 0)
+	c.Assert(bar.Plain, Equals, 1024)
+}
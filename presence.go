@@ -0,0 +1,53 @@
+package defaults
+
+import "reflect"
+
+// FieldSet holds the paths of the fields set explicitly, as returned by Field.Path, e.g. Server.Port, Servers[0] or Limits["api"].
+// A field in the set keeps its value even when it is zero, e.g. a bool set to false or a nil pointer set to null.
+type FieldSet map[string]struct{}
+
+func NewFieldSet(paths ...string) FieldSet {
+	set := make(FieldSet, len(paths))
+	for _, path := range paths {
+		set.Add(path)
+	}
+
+	return set
+}
+
+func (s FieldSet) Add(path string) {
+	s[path] = struct{}{}
+}
+
+func (s FieldSet) Has(path string) bool {
+	_, ok := s[path]
+	return ok
+}
+
+// presence tells whether the presence of fields is known for this filling, and whether the field is set explicitly
+func (f *filler) presence(field *Field) (known bool, set bool) {
+	root := field.root()
+	if root.state == nil || root.state.set == nil {
+		return false, false
+	}
+
+	return true, root.state.set.Has(field.Path())
+}
+
+// isSet tells whether the field is set explicitly, its value is never replaced as a whole
+func (f *filler) isSet(field *Field) bool {
+	_, set := f.presence(field)
+	return set
+}
+
+// isDescendable tells whether an explicitly set field holds structs which are still filled field by field
+func (f *filler) isDescendable(field *Field) bool {
+	switch field.Value.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		if field.Value.IsNil() {
+			return false
+		}
+	}
+
+	return f.isStructContainer(field)
+}
@@ -0,0 +1,111 @@
+package defaults
+
+import (
+	"net/url"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type PresenceSuite struct{}
+
+var _ = Suite(&PresenceSuite{})
+
+type ExamplePresence struct {
+	Enabled  bool          `default:"true"`
+	Retries  int           `default:"3"`
+	Name     string        `default:"app"`
+	Timeout  time.Duration `default:"5s"`
+	Started  time.Time     `default:"2020-01-01T00:00:00Z"`
+	Tags     []string      `default:"[a,b]"`
+	Verbose  *bool         `default:"true"`
+	Limit    *int          `default:"10"`
+	Admin    *Admin
+	Server   ExamplePresenceServer
+	Backends []ExamplePresenceServer
+	Omitted  ExamplePresenceServer `default:"omit"`
+}
+
+type ExamplePresenceServer struct {
+	Host string `default:"localhost"`
+	Port int    `default:"80"`
+	TLS  bool   `default:"true"`
+}
+
+func (s *PresenceSuite) TestFillUnset(c *C) {
+	verbose := false
+	foo := ExamplePresence{
+		Verbose:  &verbose,
+		Server:   ExamplePresenceServer{Host: "example.com"},
+		Backends: []ExamplePresenceServer{{Port: 8080}, {}},
+	}
+
+	err := FillUnset(&foo, NewFieldSet(
+		"Enabled", "Retries", "Tags", "Started", "Admin", "Server.TLS", "Backends[0].TLS", "Backends[1]",
+	))
+
+	c.Assert(err, IsNil)
+	// explicit zero values are kept
+	c.Assert(foo.Enabled, Equals, false)
+	c.Assert(foo.Retries, Equals, 0)
+	c.Assert(foo.Tags, IsNil)
+	c.Assert(foo.Started, Equals, time.Time{})
+	c.Assert(foo.Admin, IsNil)
+	// the others are filled as usual
+	c.Assert(foo.Name, Equals, "app")
+	c.Assert(foo.Timeout, Equals, 5*time.Second)
+	c.Assert(*foo.Limit, Equals, 10)
+	// a non-nil pointer is always set even if it points to a zero value
+	c.Assert(*foo.Verbose, Equals, false)
+	// structs are filled field by field even when they are not empty
+	c.Assert(foo.Server, Equals, ExamplePresenceServer{Host: "example.com", Port: 80})
+	c.Assert(foo.Backends, DeepEquals, []ExamplePresenceServer{
+		{Host: "localhost", Port: 8080},
+		{Host: "localhost", Port: 80, TLS: true},
+	})
+	c.Assert(foo.Omitted, Equals, ExamplePresenceServer{})
+}
+
+func (s *PresenceSuite) TestFillUnsetEmpty(c *C) {
+	var foo, bar ExamplePresence
+
+	c.Assert(FillUnset(&foo, nil), IsNil)
+	c.Assert(NewFiller(UseDefault(), UseTimeFormat(time.RFC3339), ParseDuration()).FillUnset(&bar, FieldSet{}), IsNil)
+
+	var expected ExamplePresence
+	SetDefaults(&expected)
+	c.Assert(foo, DeepEquals, expected)
+	c.Assert(bar, DeepEquals, expected)
+
+	// the same as SetDefaults except non-empty structs
+	baz := ExamplePresence{Server: ExamplePresenceServer{Port: 1}}
+
+	c.Assert(FillUnset(&baz, nil), IsNil)
+	c.Assert(baz.Server, Equals, ExamplePresenceServer{Host: "localhost", Port: 1, TLS: true})
+	c.Assert(FillUnset(baz, nil), Equals, ErrInvalidVariable)
+}
+
+func (s *PresenceSuite) TestFillUnsetParser(c *C) {
+	type Endpoint struct {
+		URL  url.URL   `default:"http://localhost"`
+		Ptr  *url.URL  `default:"http://localhost"`
+		URLs []url.URL `default:"[http://a, http://b]"`
+	}
+	filler := NewFiller(UseDefault(), UseTypeParser(func(tag string) (url.URL, error) {
+		u, err := url.Parse(tag)
+		if err != nil {
+			return url.URL{}, err
+		}
+		return *u, nil
+	}))
+
+	var foo Endpoint
+
+	c.Assert(filler.FillUnset(&foo, NewFieldSet("URL", "Ptr", "URLs")), IsNil)
+	c.Assert(foo, DeepEquals, Endpoint{})
+
+	c.Assert(filler.FillUnset(&foo, nil), IsNil)
+	c.Assert(foo.URL.Host, Equals, "localhost")
+	c.Assert(foo.Ptr.Host, Equals, "localhost")
+	c.Assert(foo.URLs, HasLen, 2)
+}
@@ -0,0 +1,104 @@
+package defaults
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Source tells where the value of a reported field comes from
+type Source int
+
+const (
+	// SourceTag is a value parsed from the tag, via FuncsByKind, encoding.TextUnmarshaler or JSON
+	SourceTag Source = iota
+	// SourceType is a value filled by FuncsByType, e.g. a registered default type
+	SourceType
+	// SourceDive is a non-empty struct filled because of the dive key
+	SourceDive
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceTag:
+		return "tag"
+	case SourceType:
+		return "type"
+	case SourceDive:
+		return "dive"
+	default:
+		return "unknown"
+	}
+}
+
+// ReportEntry describes a field changed by the filler, Previous of a field filled through a nil ptr is that nil ptr
+type ReportEntry struct {
+	Path     string
+	Previous interface{}
+	Value    interface{}
+	Source   Source
+}
+
+// Report lists every changed field in the order they are filled, nested fields always come before their struct
+type Report []ReportEntry
+
+func (r Report) String() string {
+	var b strings.Builder
+	for _, entry := range r {
+		fmt.Fprintf(&b, "%s = %v (%s)\n", entry.Path, entry.Value, entry.Source)
+	}
+
+	return b.String()
+}
+
+// apply runs fn on the field and records the change when the filling is reported
+func (f *filler) apply(field *Field, fn FillFn, source Source) {
+	state := field.root().state
+	if state == nil || !state.reporting || !f.isReported(field, source) {
+		fn(field)
+		return
+	}
+
+	if source == SourceTag && field.Value.Kind() == reflect.Struct {
+		source = SourceDive
+	}
+
+	previous := field.Value.Interface()
+	fn(field)
+
+	if value := field.Value.Interface(); !reflect.DeepEqual(previous, value) {
+		// A field behind a nil ptr is only allocated for the filling, so the ptr itself was the previous value
+		if field.nilPtr != nil {
+			previous = field.nilPtr
+		}
+		state.report = append(state.report, ReportEntry{
+			Path:     field.Path(),
+			Previous: previous,
+			Value:    value,
+			Source:   source,
+		})
+	}
+}
+
+// isReported tells whether the field is worth reporting, that is a struct field holding a value rather than
+// an element of a literal or a container of other struct fields which are reported on their own
+func (f *filler) isReported(field *Field, source Source) bool {
+	if field.segment == "" || strings.HasPrefix(field.segment, "[") {
+		return false
+	}
+	if source == SourceType {
+		return true
+	}
+
+	switch field.Value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return false
+	case reflect.Struct:
+		return field.Tag == f.DiveKey
+	case reflect.Slice, reflect.Array, reflect.Map:
+		kind := GetValueInternalKind(field.Value)
+		return kind != reflect.Struct && kind != reflect.Interface
+	default:
+		return true
+	}
+}
@@ -0,0 +1,53 @@
+package defaults
+
+import (
+	. "gopkg.in/check.v1"
+	"time"
+)
+
+type ReportSuite struct{}
+
+var _ = Suite(&ReportSuite{})
+
+type ExampleReport struct {
+	Int        int           `default:"1"`
+	IntWithVal int           `default:"1"`
+	IntPtr     *int          `default:"2"`
+	IntPtrPtr  **int         `default:"3"`
+	IntList    []int         `default:"[1,2]"`
+	Duration   time.Duration `default:"1s"`
+	Invalid    int           `default:"invalid"`
+	Default    Default
+	Struct     Struct
+	StructDive Struct   `default:"dive"`
+	StructList []Struct `default:"dive"`
+}
+
+func (s *ReportSuite) TestSetDefaultsWithReport(c *C) {
+	foo := ExampleReport{
+		IntWithVal: 7,
+		StructDive: Struct{Integer: 7},
+		StructList: []Struct{{}},
+	}
+
+	filler := NewFiller(UseDefault(), ParseDuration(), UseDefaultType(Default("7")))
+	report, err := filler.SetDefaultsWithReport(&foo)
+
+	c.Assert(err, ErrorMatches, `defaults: Invalid .*`)
+	c.Assert(report, DeepEquals, Report{
+		{Path: "Int", Previous: 0, Value: 1, Source: SourceTag},
+		{Path: "IntPtr", Previous: (*int)(nil), Value: 2, Source: SourceTag},
+		{Path: "IntPtrPtr", Previous: (**int)(nil), Value: 3, Source: SourceTag},
+		{Path: "IntList", Previous: []int(nil), Value: []int{1, 2}, Source: SourceTag},
+		{Path: "Duration", Previous: time.Duration(0), Value: time.Second, Source: SourceTag},
+		{Path: "Default", Previous: Default(""), Value: Default("7"), Source: SourceType},
+		{Path: "Struct.String", Previous: "", Value: "string", Source: SourceTag},
+		{Path: "Struct.Integer", Previous: 0, Value: 1, Source: SourceTag},
+		{Path: "StructDive.String", Previous: "", Value: "string", Source: SourceTag},
+		{Path: "StructDive", Previous: Struct{Integer: 7}, Value: Struct{String: "string", Integer: 7}, Source: SourceDive},
+		{Path: "StructList[0].String", Previous: "", Value: "string", Source: SourceTag},
+		{Path: "StructList[0].Integer", Previous: 0, Value: 1, Source: SourceTag},
+	})
+	c.Assert(report[0].Source.String(), Equals, "tag")
+	c.Assert(report[:2].String(), Equals, "Int = 1 (tag)\nIntPtr = 2 (tag)\n")
+}
@@ -0,0 +1,142 @@
+package defaults
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// UnmarshalJSON decodes data into variable by encoding/json, then fills the fields missing from data with the default filler.
+// Keys present in data are kept even when their value is zero or null, e.g. {"enabled": false}, see FillUnset.
+func UnmarshalJSON(data []byte, variable interface{}) error {
+	set, err := JSONFieldSet(data, variable)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, variable); err != nil {
+		return err
+	}
+
+	return FillUnset(variable, set)
+}
+
+// JSONFieldSet returns the paths of the fields found in data when decoded into variable, following the json tags
+// and the case-insensitive key matching of encoding/json, including elements of slices and maps
+func JSONFieldSet(data []byte, variable interface{}) (FieldSet, error) {
+	value := reflect.ValueOf(variable)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return nil, ErrInvalidVariable
+	}
+
+	var doc interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	set := FieldSet{}
+	addJSONPaths(set, value.Elem().Type(), doc, "")
+	return set, nil
+}
+
+// addJSONPaths adds the path of every value of doc to set, doc being decoded into typ
+func addJSONPaths(set FieldSet, typ reflect.Type, doc interface{}, path string) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	switch doc := doc.(type) {
+	case map[string]interface{}:
+		switch typ.Kind() {
+		case reflect.Struct:
+			addJSONFields(set, typ, doc, path)
+		case reflect.Map:
+			for key, elem := range doc {
+				elemPath := path + "[" + key + "]"
+				if typ.Key().Kind() == reflect.String {
+					elemPath = path + fmt.Sprintf("[%q]", key)
+				}
+				set.Add(elemPath)
+				addJSONPaths(set, typ.Elem(), elem, elemPath)
+			}
+		}
+	case []interface{}:
+		if typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+			for i, elem := range doc {
+				if typ.Kind() == reflect.Array && i >= typ.Len() {
+					break
+				}
+				elemPath := fmt.Sprintf("%s[%d]", path, i)
+				set.Add(elemPath)
+				addJSONPaths(set, typ.Elem(), elem, elemPath)
+			}
+		}
+	}
+}
+
+func addJSONFields(set FieldSet, typ reflect.Type, doc map[string]interface{}, path string) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, named := jsonName(field)
+		if name == "-" && !named {
+			continue
+		}
+
+		fieldPath := field.Name
+		if path != "" {
+			fieldPath = path + "." + field.Name
+		}
+
+		// Fields of embedded structs are promoted to the same object
+		embedded := field.Type
+		for embedded.Kind() == reflect.Ptr {
+			embedded = embedded.Elem()
+		}
+		if field.Anonymous && !named && embedded.Kind() == reflect.Struct {
+			addJSONFields(set, embedded, doc, fieldPath)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if elem, ok := lookupJSONKey(doc, name); ok {
+			set.Add(fieldPath)
+			addJSONPaths(set, field.Type, elem, fieldPath)
+		}
+	}
+}
+
+// jsonName returns the key of the field in a JSON object and whether it is named by the json tag
+func jsonName(field reflect.StructField) (string, bool) {
+	tag, ok := field.Tag.Lookup("json")
+	if !ok {
+		return field.Name, false
+	}
+	if tag == "-" {
+		return "-", false
+	}
+
+	name := strings.Split(tag, ",")[0]
+	if name == "" {
+		return field.Name, false
+	}
+	return name, true
+}
+
+// lookupJSONKey prefers the exact key then falls back to any key equal under case folding, like encoding/json
+func lookupJSONKey(doc map[string]interface{}, name string) (interface{}, bool) {
+	if elem, ok := doc[name]; ok {
+		return elem, true
+	}
+	for key, elem := range doc {
+		if strings.EqualFold(key, name) {
+			return elem, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,89 @@
+package defaults
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type UnmarshalSuite struct{}
+
+var _ = Suite(&UnmarshalSuite{})
+
+type ExampleUnmarshal struct {
+	Enabled  bool                              `json:"enabled" default:"true"`
+	Retries  int                               `json:"retries,omitempty" default:"3"`
+	Name     string                            `default:"app"`
+	Timeout  time.Duration                     `json:"timeout" default:"5s"`
+	Ignored  string                            `json:"-" default:"ignored"`
+	Dash     string                            `json:"-," default:"dash"`
+	Admin    *Admin                            `json:"admin"`
+	Server   ExamplePresenceServer             `json:"server"`
+	Backends []ExamplePresenceServer           `json:"backends"`
+	Limits   map[string]ExamplePresenceServer  `json:"limits"`
+	Pointers map[string]*ExamplePresenceServer `json:"pointers"`
+	Tags     []string                          `json:"tags" default:"[a,b]"`
+	ExampleUnmarshalEmbedded
+}
+
+type ExampleUnmarshalEmbedded struct {
+	Region string `json:"region" default:"eu"`
+	Zone   string `json:"zone" default:"a"`
+}
+
+func (s *UnmarshalSuite) TestUnmarshalJSON(c *C) {
+	var foo ExampleUnmarshal
+
+	err := UnmarshalJSON([]byte(`{
+		"enabled": false,
+		"RETRIES": 0,
+		"Ignored": "x",
+		"-": "",
+		"admin": null,
+		"server": {"host": "example.com", "tls": false},
+		"backends": [{"port": 0}, {}],
+		"limits": {"api": {"port": 1}},
+		"pointers": {"a": null, "b": {}},
+		"tags": [],
+		"zone": ""
+	}`), &foo)
+
+	c.Assert(err, IsNil)
+	c.Assert(foo.Enabled, Equals, false)
+	c.Assert(foo.Retries, Equals, 0)
+	c.Assert(foo.Name, Equals, "app")
+	c.Assert(foo.Timeout, Equals, 5*time.Second)
+	c.Assert(foo.Ignored, Equals, "ignored")
+	c.Assert(foo.Dash, Equals, "")
+	c.Assert(foo.Admin, IsNil)
+	c.Assert(foo.Server, Equals, ExamplePresenceServer{Host: "example.com", Port: 80})
+	c.Assert(foo.Backends, DeepEquals, []ExamplePresenceServer{
+		{Host: "localhost", Port: 0, TLS: true},
+		{Host: "localhost", Port: 80, TLS: true},
+	})
+	c.Assert(foo.Limits, DeepEquals, map[string]ExamplePresenceServer{"api": {Host: "localhost", Port: 1, TLS: true}})
+	c.Assert(foo.Pointers, DeepEquals, map[string]*ExamplePresenceServer{
+		"a": nil,
+		"b": {Host: "localhost", Port: 80, TLS: true},
+	})
+	c.Assert(foo.Tags, DeepEquals, []string{})
+	c.Assert(foo.Region, Equals, "eu")
+	c.Assert(foo.Zone, Equals, "")
+}
+
+func (s *UnmarshalSuite) TestJSONFieldSet(c *C) {
+	set, err := JSONFieldSet([]byte(`{"server": {"port": 1}, "backends": [{"HOST": "a"}], "limits": {"api": {}}, "unknown": 1}`),
+		&ExampleUnmarshal{})
+
+	c.Assert(err, IsNil)
+	c.Assert(set, DeepEquals, NewFieldSet(
+		"Server", "Server.Port", "Backends", "Backends[0]", "Backends[0].Host", "Limits", `Limits["api"]`,
+	))
+
+	_, err = JSONFieldSet([]byte(`{`), &ExampleUnmarshal{})
+	c.Assert(err, ErrorMatches, "unexpected EOF")
+
+	var foo ExampleUnmarshal
+	c.Assert(UnmarshalJSON([]byte(`{"enabled": "yes"}`), &foo), ErrorMatches, "json: cannot unmarshal .*")
+	c.Assert(UnmarshalJSON([]byte(`{}`), foo), Equals, ErrInvalidVariable)
+}
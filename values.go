@@ -0,0 +1,212 @@
+package defaults
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const keyTag = "key"
+
+// ParseKeyValues reads KEY=value lines in dotenv or Java properties style, e.g.
+//
+//	# comment
+//	export DB_HOST=localhost
+//	server.port: 8080
+//	message = "hello\nworld"
+//	servers = [a, \
+//	           b]
+//
+// Keys and values are separated by the first = or :, blank lines and lines starting with # or ! are skipped.
+// Values may be quoted with " to resolve escapes or with ' to keep them as is, a trailing backslash continues the line.
+func ParseKeyValues(r io.Reader) (map[string]string, error) {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+
+	var lineNum int
+	for scanner.Scan() {
+		lineNum++
+		line, start := strings.TrimSpace(scanner.Text()), lineNum
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+
+		// Join the continued lines of properties style
+		for strings.HasSuffix(line, `\`) && !strings.HasSuffix(line, `\\`) && scanner.Scan() {
+			lineNum++
+			line = line[:len(line)-1] + strings.TrimSpace(scanner.Text())
+		}
+
+		key, value, err := parseKeyValue(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", start, err)
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+func parseKeyValue(line string) (string, string, error) {
+	line = strings.TrimPrefix(line, "export ")
+
+	sep := strings.IndexAny(line, "=:")
+	if sep < 0 {
+		return "", "", fmt.Errorf("%w: missing '=' in %q", ErrMalformedEntry, line)
+	}
+	key, value := strings.TrimSpace(line[:sep]), strings.TrimSpace(line[sep+1:])
+	if key == "" {
+		return "", "", fmt.Errorf("%w: missing key in %q", ErrMalformedEntry, line)
+	}
+
+	if value == "" || value[0] != '"' && value[0] != '\'' {
+		return key, value, nil
+	}
+
+	quote := value[0]
+	var b strings.Builder
+	for i := 1; i < len(value); i++ {
+		switch c := value[i]; {
+		case c == quote:
+			if rest := strings.TrimSpace(value[i+1:]); rest != "" && rest[0] != '#' {
+				return "", "", fmt.Errorf("%w: unexpected %q after quoted value", ErrMalformedEntry, rest)
+			}
+			return key, b.String(), nil
+		case c == '\\' && quote == '"' && i+1 < len(value):
+			i++
+			switch value[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(value[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", "", fmt.Errorf("%w: unterminated quote in %q", ErrMalformedEntry, value)
+}
+
+// lookupValue returns the value of the field found by its key tag, or else by its path, e.g. server.readtimeout,
+// or by its path in snake case, e.g. SERVER_READ_TIMEOUT, all case-insensitive
+func (f *filler) lookupValue(field *Field) (string, bool) {
+	if key := field.StructField.Tag.Get(keyTag); key != "" {
+		value, ok := f.Values[strings.ToLower(key)]
+		return value, ok
+	}
+
+	if value, ok := f.Values[strings.ToLower(field.Path())]; ok {
+		return value, true
+	}
+	value, ok := f.Values[snakePath(field)]
+	return value, ok
+}
+
+// snakePath returns the lowercase path of the field with every word separated by _, e.g. server_read_timeout
+// of Server.ReadTimeout, http_port of HTTPPort or servers_0_host of Servers[0].Host
+func snakePath(field *Field) string {
+	var words []string
+	for p := field; p != nil; p = p.Parent {
+		if p.segment != "" {
+			words = append(words, snakeSegment(p.segment))
+		}
+	}
+
+	for i, j := 0, len(words)-1; i < j; i, j = i+1, j-1 {
+		words[i], words[j] = words[j], words[i]
+	}
+	return strings.Join(words, "_")
+}
+
+func snakeSegment(segment string) string {
+	// Index of a slice or key of a map, e.g. [0] or ["api"]
+	if strings.HasPrefix(segment, "[") {
+		index := segment[1 : len(segment)-1]
+		if key, err := strconv.Unquote(index); err == nil {
+			index = key
+		}
+		return strings.ToLower(index)
+	}
+
+	runes := []rune(segment)
+	var b strings.Builder
+	for i, r := range runes {
+		// A word starts at an upper case letter after a lower case one, or before one in an acronym, e.g. HTTPPort
+		if i > 0 && unicode.IsUpper(r) &&
+			(unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// addValues merges the values into the filler, later values take precedence
+func (f *filler) addValues(values map[string]string) {
+	if f.Values == nil {
+		f.Values = make(map[string]string, len(values))
+	}
+	for key, value := range values {
+		f.Values[strings.ToLower(key)] = value
+	}
+}
+
+// loadDirectory reads every file in dir as a value keyed by the file name, trailing newlines are trimmed.
+// Hidden files like ..data of Kubernetes mounts are skipped.
+func loadDirectory(dir string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		// Follow symlinks, mounted keys are links to the current version of the data
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		values[name] = strings.TrimRight(string(content), "\r\n")
+	}
+
+	return values, nil
+}
+
+func loadKeyValueFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values, err := ParseKeyValues(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return values, nil
+}
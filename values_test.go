@@ -0,0 +1,195 @@
+package defaults
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type ValuesSuite struct{}
+
+var _ = Suite(&ValuesSuite{})
+
+func (s *ValuesSuite) TestParseKeyValues(c *C) {
+	values, err := ParseKeyValues(strings.NewReader(`
+# dotenv
+export DB_HOST=db.local
+DB_PORT = 5432
+EMPTY=
+URL=http://localhost:8080/a=b
+QUOTED="hello\n\"world\"" # comment
+SINGLE='C:\dir'
+
+! properties
+server.port: 8080
+servers = [a, \
+           b]
+`))
+
+	c.Assert(err, IsNil)
+	c.Assert(values, DeepEquals, map[string]string{
+		"DB_HOST":     "db.local",
+		"DB_PORT":     "5432",
+		"EMPTY":       "",
+		"URL":         "http://localhost:8080/a=b",
+		"QUOTED":      "hello\n\"world\"",
+		"SINGLE":      `C:\dir`,
+		"server.port": "8080",
+		"servers":     "[a, b]",
+	})
+
+	for input, msg := range map[string]string{
+		"A=1\nB":         `line 2: malformed entry: missing '=' in "B"`,
+		"A=1\n\n=2":      `line 3: malformed entry: missing key in "=2"`,
+		"A=\\\n1\nB=\"x": `line 3: malformed entry: unterminated quote in "\\"x"`,
+		"# A=1\nB='x' y": `line 2: malformed entry: unexpected "y" after quoted value`,
+	} {
+		_, err := ParseKeyValues(strings.NewReader(input))
+		c.Assert(err, ErrorMatches, msg)
+		c.Assert(errors.Is(err, ErrMalformedEntry), Equals, true)
+	}
+}
+
+type ExampleValues struct {
+	Host     string        `key:"DB_HOST" default:"localhost"`
+	Port     int           `key:"db_port" default:"5432"`
+	User     string        `env:"VALUES_USER" default:"admin"`
+	Timeout  time.Duration `default:"1s"`
+	Tags     []string      `default:"[a]"`
+	Missing  string        `default:"tag"`
+	Server   ExampleFlagsServer
+	Backends []ExampleFlagsServer `default:"dive"`
+	HTTP     ExampleValuesHTTP
+	Omitted  ExampleValuesHTTP `default:"omit"`
+}
+
+type ExampleValuesHTTP struct {
+	ReadTimeout time.Duration `default:"1s"`
+	MaxBodySize int           `default:"1024"`
+}
+
+func (s *ValuesSuite) TestUseValues(c *C) {
+	c.Assert(os.Setenv("VALUES_USER", "env"), IsNil)
+	defer os.Unsetenv("VALUES_USER")
+
+	foo := ExampleValues{Backends: []ExampleFlagsServer{{}}}
+	filler := NewFiller(UseDefault(), ParseDuration(), UseEnvTag("env"), UseValues(map[string]string{
+		"db_host":          "db.local",
+		"DB_PORT":          "6543",
+		"user":             "values",
+		"timeout":          "5s",
+		"TAGS":             "[x, y]",
+		"server.port":      "8080",
+		"backends[0].host": "backend",
+		"http":             "omit",
+		"omitted":          "dive",
+	}))
+
+	c.Assert(filler.SetDefaultsE(&foo), IsNil)
+	c.Assert(foo, DeepEquals, ExampleValues{
+		Host:     "db.local",
+		Port:     6543,
+		User:     "env",
+		Timeout:  5 * time.Second,
+		Tags:     []string{"x", "y"},
+		Missing:  "tag",
+		Server:   ExampleFlagsServer{Host: "example.com", Port: 8080},
+		Backends: []ExampleFlagsServer{{Host: "backend", Port: 80}},
+		HTTP:     ExampleValuesHTTP{ReadTimeout: time.Second, MaxBodySize: 1024},
+	})
+
+	// values are parsed like tags
+	var bar ExampleValues
+	err := NewFiller(UseDefault(), UseValues(map[string]string{"db_port": "invalid"})).SetDefaultsE(&bar)
+
+	c.Assert(err, ErrorMatches, `defaults: Port \(int\): invalid default "invalid": .*`)
+}
+
+func (s *ValuesSuite) TestUseKeyValueFile(c *C) {
+	path := filepath.Join(c.MkDir(), "app.env")
+	c.Assert(os.WriteFile(path, []byte("DB_HOST=db.local\nserver.host: api\nSERVER_PORT=8080\nHTTP_READ_TIMEOUT=1m\n"), 0o600), IsNil)
+
+	var foo ExampleValues
+
+	c.Assert(NewFiller(UseDefault(), ParseDuration(), UseKeyValueFile(path)).SetDefaultsE(&foo), IsNil)
+	c.Assert(foo.Host, Equals, "db.local")
+	c.Assert(foo.Server, Equals, ExampleFlagsServer{Host: "api", Port: 8080})
+	c.Assert(foo.HTTP.ReadTimeout, Equals, time.Minute)
+
+	// a directory with a file per key fills the very same fields
+	dir := c.MkDir()
+	for name, content := range map[string]string{"DB_HOST": "db.local", "server.host": "api", "SERVER_PORT": "8080", "HTTP_READ_TIMEOUT": "1m"} {
+		c.Assert(os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600), IsNil)
+	}
+	var baz ExampleValues
+
+	c.Assert(NewFiller(UseDefault(), ParseDuration(), UseDirectory(dir)).SetDefaultsE(&baz), IsNil)
+	c.Assert(baz, DeepEquals, foo)
+
+	// fields are still filled from tags when the file fails to load
+	var bar ExampleValues
+	err := NewFiller(UseDefault(), ParseDuration(), UseKeyValueFile(path+".missing")).SetDefaultsE(&bar)
+
+	c.Assert(errors.Is(err, os.ErrNotExist), Equals, true)
+	c.Assert(bar.Host, Equals, "localhost")
+
+	c.Assert(os.WriteFile(path, []byte("A=1\nB\n"), 0o600), IsNil)
+	err = NewFiller(UseDefault(), ParseDuration(), UseKeyValueFile(path)).SetDefaultsE(&bar)
+
+	c.Assert(err, ErrorMatches, `defaults: .*app.env: line 2: malformed entry: .*`)
+}
+
+func (s *ValuesSuite) TestUseDirectory(c *C) {
+	dir := c.MkDir()
+	data := filepath.Join(dir, "..data")
+	c.Assert(os.Mkdir(data, 0o700), IsNil)
+	for name, content := range map[string]string{
+		"DB_HOST":          "db.local\n",
+		"SERVER_PORT":      "8080\r\n",
+		"server.host":      "api\n\n",
+		"TAGS":             "[x, y]\n",
+		"backends[0].host": "backend",
+		"timeout":          "5s",
+		// words of a field are separated like the fields
+		"HTTP_READ_TIMEOUT": "1m\n",
+		"http.maxbodysize":  "2048",
+	} {
+		c.Assert(os.WriteFile(filepath.Join(data, name), []byte(content), 0o600), IsNil)
+		// mounted keys are links to the current data
+		c.Assert(os.Symlink(filepath.Join("..data", name), filepath.Join(dir, name)), IsNil)
+	}
+
+	foo := ExampleValues{Backends: []ExampleFlagsServer{{}, {}}}
+
+	c.Assert(NewFiller(UseDefault(), ParseDuration(), UseDirectory(dir)).SetDefaultsE(&foo), IsNil)
+	c.Assert(foo, DeepEquals, ExampleValues{
+		Host:     "db.local",
+		Port:     5432,
+		User:     "admin",
+		Timeout:  5 * time.Second,
+		Tags:     []string{"x", "y"},
+		Missing:  "tag",
+		Server:   ExampleFlagsServer{Host: "api", Port: 8080},
+		Backends: []ExampleFlagsServer{{Host: "backend", Port: 80}, {Host: "example.com", Port: 80}},
+		HTTP:     ExampleValuesHTTP{ReadTimeout: time.Minute, MaxBodySize: 2048},
+	})
+
+	// fields are still filled from tags when the directory is missing
+	var bar ExampleValues
+	err := NewFiller(UseDefault(), ParseDuration(), UseDirectory(filepath.Join(dir, "missing"))).SetDefaultsE(&bar)
+
+	c.Assert(errors.Is(err, os.ErrNotExist), Equals, true)
+	c.Assert(bar.Host, Equals, "localhost")
+	// every source failing to load is reported
+	var baz ExampleValues
+	err = NewFiller(UseDefault(), ParseDuration(), UseKeyValueFile(filepath.Join(dir, "missing.env")),
+		UseDirectory(filepath.Join(dir, "missing")), UseDirectory(dir)).SetDefaultsE(&baz)
+
+	c.Assert(err, ErrorMatches, `defaults: open .*missing.env: .*; open .*missing: .*`)
+	c.Assert(err.(Errors), HasLen, 2)
+	c.Assert(baz.Host, Equals, "db.local")
+}